@@ -0,0 +1,249 @@
+// mockoidc 是用于本地开发和联调的 OIDC 身份提供方，不要用于生产环境
+//
+// 用法：
+//
+//	go run ./cmd/mockoidc -addr :9000
+//
+// 服务端配置 OIDC_ISSUER=http://localhost:9000、OIDC_CLIENT_ID=topservice 后即可登录。
+// 授权页可填写邮箱和用户组；授权请求带 login_hint（及可选的 groups，逗号分隔、email_verified=false）时直接跳过授权页，便于脚本测试。
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"topService/internal/jwt"
+)
+
+const keyID = "mock-key"
+
+// authorization 授权码对应的登录信息
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	challenge     string
+	email         string
+	groups        []string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+type provider struct {
+	issuer   string
+	clientID string
+	key      *rsa.PrivateKey
+	
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Mock OIDC</title></head>
+<body>
+<h1>Mock OIDC 登录</h1>
+<form method="post" action="/authorize">
+{{range $k, $v := .Query}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}
+<p><label>邮箱 <input name="login_hint" value="alice@example.com"></label></p>
+<p><label>用户组（逗号分隔） <input name="groups" value=""></label></p>
+<p><label>邮箱已验证 <select name="email_verified"><option value="true">是</option><option value="false">否</option></select></label></p>
+<button type="submit">登录</button>
+</form>
+</body></html>`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "监听地址")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer，需与服务端 OIDC_ISSUER 一致")
+	clientID := flag.String("client-id", "topservice", "允许的 client_id")
+	flag.Parse()
+	
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("Failed to generate RSA key:", err)
+	}
+	
+	p := &provider{
+		issuer:   strings.TrimSuffix(*issuer, "/"),
+		clientID: *clientID,
+		key:      key,
+		codes:    make(map[string]*authorization),
+	}
+	
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	
+	log.Printf("Mock OIDC provider listening on %s (issuer %s)", *addr, p.issuer)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := r.Form
+	
+	if form.Get("client_id") != p.clientID || form.Get("response_type") != "code" {
+		http.Error(w, "invalid client_id or response_type", http.StatusBadRequest)
+		return
+	}
+	if form.Get("code_challenge") == "" || form.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(form.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	
+	email := form.Get("login_hint")
+	if email == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginPage.Execute(w, map[string]interface{}{"Query": r.URL.Query()})
+		return
+	}
+	
+	var groups []string
+	for _, group := range strings.Split(form.Get("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = &authorization{
+		clientID:      form.Get("client_id"),
+		redirectURI:   form.Get("redirect_uri"),
+		nonce:         form.Get("nonce"),
+		challenge:     form.Get("code_challenge"),
+		email:         email,
+		groups:        groups,
+		emailVerified: form.Get("email_verified") != "false",
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+	
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", form.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	auth := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	
+	clientID := r.PostForm.Get("client_id")
+	if basicID, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(basicID)
+	}
+	
+	switch {
+	case auth == nil || time.Now().After(auth.expiresAt):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case clientID != auth.clientID || r.PostForm.Get("redirect_uri") != auth.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "client_id or redirect_uri mismatch"})
+		return
+	}
+	
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+	
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":                p.issuer,
+		"sub":                "mock|" + auth.email,
+		"aud":                auth.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              auth.nonce,
+		"email":              auth.email,
+		"email_verified":     auth.emailVerified,
+		"preferred_username": strings.SplitN(auth.email, "@", 2)[0],
+		"groups":             auth.groups,
+	}
+	idToken, err := jwt.SignRS256(claims, p.key, keyID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -3,9 +3,19 @@ module topService
 go 1.16
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
+	github.com/andybalholm/brotli v1.0.4
+	github.com/evanphx/json-patch/v5 v5.6.0
 	github.com/gin-gonic/gin v1.7.7
+	github.com/go-redis/redis/v8 v8.11.5
 	github.com/joho/godotenv v1.4.0
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/xuri/excelize/v2 v2.6.1
+	golang.org/x/crypto v0.0.0-20220817201139-bc19a97f63c8
+	golang.org/x/net v0.0.0-20220812174116-3211cb980234
+	golang.org/x/sync v0.0.0-20220907140024-f12130a52804
 	gorm.io/driver/mysql v1.3.6
 	gorm.io/driver/sqlite v1.3.6
 	gorm.io/gorm v1.23.8
+	gorm.io/plugin/dbresolver v1.2.3
 )
@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache 键值缓存，值为调用方序列化后的字节
+// 单实例部署使用进程内的 LRU，多实例部署使用 Redis 在实例间共享
+type Cache interface {
+	// Get 读取 key，不存在或已过期时 ok 为 false
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	
+	// Set 写入 key，ttl 不大于 0 时不过期（LRU 仍可能在容量不足时淘汰）
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	
+	// Delete 删除 keys，不存在的键忽略
+	Delete(ctx context.Context, keys ...string) error
+}
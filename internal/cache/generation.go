@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Generation 读取命名空间当前的代号，不存在时（首次使用或被淘汰）生成新的代号
+// 缓存键带上命名空间的代号，Rotate 更换代号后命名空间下的旧键全部失效并随 TTL 过期，无需逐个删除
+func Generation(ctx context.Context, c Cache, namespace string) (string, error) {
+	data, ok, err := c.Get(ctx, namespace+":generation")
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(data), nil
+	}
+	return Rotate(ctx, c, namespace)
+}
+
+// Rotate 为命名空间生成新的代号
+func Rotate(ctx context.Context, c Cache, namespace string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	generation := hex.EncodeToString(buf)
+	if err := c.Set(ctx, namespace+":generation", []byte(generation), 0); err != nil {
+		return "", err
+	}
+	return generation, nil
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	// 读取 a 后 b 成为最久未使用的条目
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	c.Set(ctx, "c", []byte("3"), 0)
+	
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%s should be cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUOverwriteKeepsSize(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "a", []byte("2"), 0)
+	
+	value, ok, _ := c.Get(ctx, "a")
+	if !ok || string(value) != "2" {
+		t.Errorf("Get(a) = %q, %v, want \"2\", true", value, ok)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	
+	c.Set(ctx, "short", []byte("1"), 20*time.Millisecond)
+	c.Set(ctx, "forever", []byte("2"), 0)
+	time.Sleep(40 * time.Millisecond)
+	
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("short should have expired")
+	}
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("entries without ttl should not expire")
+	}
+	// 过期的条目在读取时删除
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestLRUDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("a should have been deleted")
+	}
+	if _, ok, _ := c.Get(ctx, "b"); !ok {
+		t.Error("b should be cached")
+	}
+}
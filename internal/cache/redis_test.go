@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestRedis 创建连接到内嵌 Redis 的缓存
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return NewRedis(client, "test:"), server
+}
+
+func TestRedisGetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+	
+	if _, ok, err := c.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(missing) = %v, %v, want false, nil", ok, err)
+	}
+	
+	if err := c.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := c.Get(ctx, "a")
+	if err != nil || !ok || string(value) != "1" {
+		t.Fatalf("Get(a) = %q, %v, %v, want \"1\", true, nil", value, ok, err)
+	}
+	// 键带有前缀
+	if !server.Exists("test:a") {
+		t.Error("key should be stored with prefix")
+	}
+	
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("a should have been deleted")
+	}
+	if err := c.Delete(ctx); err != nil {
+		t.Errorf("Delete() without keys: %v", err)
+	}
+}
+
+func TestRedisExpires(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+	
+	c.Set(ctx, "short", []byte("1"), time.Minute)
+	c.Set(ctx, "forever", []byte("2"), 0)
+	server.FastForward(2 * time.Minute)
+	
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("short should have expired")
+	}
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("entries without ttl should not expire")
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+	server.Close()
+	
+	if _, _, err := c.Get(ctx, "a"); err == nil {
+		t.Error("Get should fail when redis is down")
+	}
+	if err := c.Set(ctx, "a", []byte("1"), 0); err == nil {
+		t.Error("Set should fail when redis is down")
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,13 +19,159 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	
+	// 读库配置（MySQL 只读副本）
+	DBReplicaHosts  []string // host:port 列表，为空时不启用读写分离
+	DBReplicaPolicy string   // random 或 round_robin
+	
 	// 服务器配置
-	ServerHost string
-	ServerPort string
+	ServerHost      string
+	ServerPort      string
+	ShutdownTimeout time.Duration // 收到退出信号后等待请求和执行中的任务结束的最长时长
 	
 	// 应用配置
 	AppEnv   string
 	AppDebug bool
+	
+	// 并发控制配置
+	RequireIfMatch bool // PUT/DELETE 是否必须携带 If-Match
+	
+	// 回收站配置
+	SoftDeleteRetention time.Duration // 软删除数据保留时长，由定时任务 trash.purge 清理
+	
+	// 幂等键配置
+	IdempotencyTTL             time.Duration // 幂等键保存时长，期间重试将回放首次响应
+	IdempotencyCleanupInterval time.Duration // 过期幂等记录清理间隔，0 表示不自动清理
+	
+	// 限流配置，键为路由分组名，未配置的分组使用 default
+	RateLimitEnabled       bool
+	RateLimitAnonymous     map[string]RateLimit // 未认证请求按 IP 计算
+	RateLimitAuthenticated map[string]RateLimit // 已认证请求按用户或 API Key 计算
+	
+	// 跨域配置
+	CORSAllowedOrigins   []string // 精确匹配或 https://*.example.com 形式的子域名通配，* 表示任意来源
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	
+	// 安全配置
+	TrustedProxies      []string      // 可信代理的 IP 或 CIDR，只有来自这些地址的 X-Forwarded-For 才会被采信
+	HSTSMaxAge          time.Duration // Strict-Transport-Security 有效期，0 表示不发送
+	MaxBodySize         int64         // 普通请求体大小上限（字节）
+	MaxBatchBodySize    int64         // 批量接口请求体大小上限（字节）
+	MaxImportBodySize   int64         // 导入接口请求体大小上限（字节）
+	DisallowUnknownJSON bool          // 请求 JSON 中出现未知字段时拒绝
+	
+	// 认证配置
+	AuthRequired    bool   // 为 true 时 /api/v1 下的接口都需要认证，管理员接口始终需要认证
+	APIKeyBootstrap string // 尚无可用 API Key 时用该明文创建拥有全部权限的初始密钥
+	
+	// 会话配置
+	SessionSecret string        // 会话令牌签名密钥，为空时启动时随机生成（重启后已签发的令牌失效）
+	SessionTTL    time.Duration // 会话令牌有效期
+	
+	// OIDC 单点登录配置，Issuer、ClientID、RedirectURL 都配置后启用
+	OIDCIssuer            string
+	OIDCClientID          string
+	OIDCClientSecret      string // 公共客户端（仅 PKCE）可不配置
+	OIDCRedirectURL       string // 本服务的回调地址，如 https://api.example.com/api/v1/auth/oidc/callback
+	OIDCScopes            []string
+	OIDCGroupsClaim       string            // ID Token 中表示用户组的声明名
+	OIDCRoleMapping       map[string]string // IdP 用户组 -> 角色
+	OIDCPostLoginRedirect string            // 登录成功后跳转的前端地址，令牌放在 URL 片段中；为空时直接返回 JSON
+	
+	// 邮件配置
+	MailDriver    string // smtp 或 file（写入 MailOutboxDir，用于开发和测试）
+	MailFrom      string
+	MailOutboxDir string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	
+	// 邮箱验证与密码重置配置
+	EmailVerifyURL        string // 验证邮件中的链接地址，令牌以 ?token= 附加
+	PasswordResetURL      string // 重置密码邮件中的链接地址（前端页面），令牌以 ?token= 附加
+	EmailChangeURL        string // 修改邮箱确认邮件中的链接地址，令牌以 ?token= 附加，有效期同邮箱验证令牌
+	EmailVerifyTokenTTL   time.Duration
+	PasswordResetTokenTTL time.Duration
+	RequireVerifiedEmail  bool // 为 true 时未验证邮箱的用户不能执行修改类操作
+	
+	// 两步验证配置
+	TOTPIssuer             string        // 验证器应用中显示的服务名
+	TwoFactorChallengeTTL  time.Duration // 密码登录通过后提交动态码的时限
+	TwoFactorRequiredRoles []string      // 访问特权接口前必须通过两步验证的角色
+	
+	// 登录防暴力破解配置
+	LoginMaxFailures     int           // 同一账号连续失败多少次后锁定
+	LoginIPMaxFailures   int           // 同一 IP 连续失败多少次后锁定
+	LoginLockoutDuration time.Duration // 锁定时长
+	LoginDelayBase       time.Duration // 第二次失败起，下次尝试前需等待的基础时长，每次失败翻倍
+	LoginFailureWindow   time.Duration // 超过该时长没有新的失败时清零计数
+	
+	// 领域事件与 Webhook 配置
+	EventRetention          time.Duration // 已分发的事件和投递记录保留时长
+	WebhookDispatchInterval time.Duration // 轮询待分发事件和待投递记录的间隔，0 表示不投递
+	WebhookTimeout          time.Duration // 单次投递的超时时间
+	WebhookMaxAttempts      int           // 最多投递次数，用完后进入死信状态
+	WebhookBackoffBase      time.Duration // 首次重试前的等待时长，之后每次翻倍
+	WebhookBackoffMax       time.Duration // 重试等待时长上限
+	WebhookConcurrency      int           // 同时进行的投递数
+	
+	// 事件流配置
+	EventStreamPollInterval time.Duration // 轮询新事件的间隔，0 表示不推送
+	EventStreamHeartbeat    time.Duration // 心跳间隔，避免空闲连接被代理断开
+	EventStreamMaxDuration  time.Duration // 单个连接的最长时长，到期断开后客户端续传并重新认证，0 表示不限制
+	
+	// 后台任务配置
+	JobWorkers      int           // 本实例的 worker 数，0 表示只入队不执行
+	JobPollInterval time.Duration // 空闲 worker 轮询到期任务的间隔
+	JobLease        time.Duration // 任务租约时长，worker 失联超过该时长后任务由其他 worker 接管
+	JobMaxAttempts  int           // 默认最多执行次数
+	JobBackoffBase  time.Duration // 首次重试前的等待时长，之后每次翻倍
+	JobBackoffMax   time.Duration // 重试等待时长上限
+	JobRetention    time.Duration // 已结束的任务及导出文件保留时长
+	ExportDir       string        // 异步导出文件的存放目录
+	
+	// 播放地址检查配置
+	VideoCheckTimeout time.Duration // 单个播放地址的请求超时时间
+	
+	// 定时任务配置，cron 表达式为五段格式或 @hourly、@every 1h 等描述符，off 表示只能手动触发
+	SchedulerPollInterval time.Duration // 检查到期任务的间隔，0 表示本实例不执行到期任务
+	TaskRunRetention      time.Duration // 执行记录保留时长
+	MovieStatsSchedule    string        // 重新计算电影统计
+	TrashPurgeSchedule    string        // 彻底删除超过保留期的软删除数据
+	ExpireTokensSchedule  string        // 清理过期的会话和一次性令牌
+	VideoCheckSchedule    string        // 检查全部电影的播放地址
+	
+	// 缓存配置
+	CacheDriver        string        // memory（进程内 LRU）、redis 或 none（不缓存）
+	CacheSize          int           // memory 驱动最多缓存的条目数
+	RedisAddr          string        // redis 驱动的服务地址
+	RedisPassword      string
+	RedisDB            int
+	RedisKeyPrefix     string        // 缓存键前缀，与其他应用共用 Redis 时避免冲突
+	MovieListCacheTTL  time.Duration // 高评分、按类型电影列表的缓存时长，电影变化后提前失效
+	MovieStatsCacheTTL time.Duration // 电影统计的缓存时长，统计重新计算后提前失效
+	
+	// HTTP 响应配置
+	CompressionEnabled bool          // 是否按 Accept-Encoding 以 brotli/gzip 压缩响应
+	CompressionMinSize int           // 小于该字节数的响应不压缩
+	PublicCacheMaxAge  time.Duration // 公开电影接口的 Cache-Control max-age
+	ResponseCacheTTL   time.Duration // 公开电影列表在服务端的响应缓存时长，0 表示不缓存
+}
+
+// defaultCORSOrigins 各环境默认允许的跨域来源，生产环境默认不允许跨域
+var defaultCORSOrigins = map[string][]string{
+	"development": {"http://localhost:3000", "http://127.0.0.1:3000"},
+	"testing":     {"http://localhost:3000", "http://127.0.0.1:3000"},
+}
+
+// RateLimit 限流配额：每 Period 最多 Requests 次请求，允许一次性用完
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
 }
 
 func Load() *Config {
@@ -31,6 +180,18 @@ func Load() *Config {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 	
+	appEnv := getEnv("APP_ENV", "development")
+	
+	corsOrigins := getEnvList("CORS_ALLOWED_ORIGINS")
+	if len(corsOrigins) == 0 {
+		corsOrigins = defaultCORSOrigins[appEnv]
+	}
+	
+	hstsMaxAge := time.Duration(0)
+	if appEnv == "production" {
+		hstsMaxAge = 180 * 24 * time.Hour
+	}
+	
 	return &Config{
 		DBType:     getEnv("DB_TYPE", "mysql"),
 		DBHost:     getEnv("DB_HOST", "117.72.67.244"),
@@ -39,11 +200,124 @@ func Load() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "A123456"),
 		DBName:     getEnv("DB_NAME", "topservice_db"),
 		
-		ServerHost: getEnv("SERVER_HOST", "0.0.0.0"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+		DBReplicaHosts:  getEnvList("DB_REPLICA_HOSTS"),
+		DBReplicaPolicy: getEnv("DB_REPLICA_POLICY", "random"),
 		
-		AppEnv:   getEnv("APP_ENV", "development"),
+		ServerHost:      getEnv("SERVER_HOST", "0.0.0.0"),
+		ServerPort:      getEnv("SERVER_PORT", "8080"),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		
+		AppEnv:   appEnv,
 		AppDebug: getEnv("APP_DEBUG", "true") == "true",
+		
+		RequireIfMatch: getEnv("REQUIRE_IF_MATCH", "false") == "true",
+		
+		SoftDeleteRetention: getEnvDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour),
+		
+		IdempotencyTTL:             getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyCleanupInterval: getEnvDuration("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour),
+		
+		RateLimitEnabled:       getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+		RateLimitAnonymous:     getEnvRateLimits("RATE_LIMIT_ANONYMOUS", "default=300/1m,movie_search=30/1m,admin=30/1m,auth=30/1m"),
+		RateLimitAuthenticated: getEnvRateLimits("RATE_LIMIT_AUTHENTICATED", "default=1200/1m,movie_search=120/1m,admin=300/1m"),
+		
+		CORSAllowedOrigins:   corsOrigins,
+		CORSAllowedMethods:   getEnvListDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
+		CORSAllowedHeaders:   getEnvListDefault("CORS_ALLOWED_HEADERS", "Origin,Content-Type,Accept,Authorization,X-API-Key,X-Request-ID,If-Match,If-None-Match,Idempotency-Key,Last-Event-ID"),
+		CORSExposedHeaders:   getEnvListDefault("CORS_EXPOSED_HEADERS", "Content-Length,Content-Disposition,ETag,Location,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Idempotent-Replayed,X-Request-ID"),
+		CORSAllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 24*time.Hour),
+		
+		TrustedProxies:      getEnvList("TRUSTED_PROXIES"),
+		HSTSMaxAge:          getEnvDuration("HSTS_MAX_AGE", hstsMaxAge),
+		MaxBodySize:         getEnvInt64("MAX_BODY_SIZE", 1<<20),
+		MaxBatchBodySize:    getEnvInt64("MAX_BATCH_BODY_SIZE", 16<<20),
+		MaxImportBodySize:   getEnvInt64("MAX_IMPORT_BODY_SIZE", 21<<20),
+		DisallowUnknownJSON: getEnv("DISALLOW_UNKNOWN_JSON_FIELDS", "false") == "true",
+		
+		AuthRequired:    getEnv("AUTH_REQUIRED", "false") == "true",
+		APIKeyBootstrap: getEnv("API_KEY_BOOTSTRAP", ""),
+		
+		SessionSecret: getEnv("SESSION_SECRET", ""),
+		SessionTTL:    getEnvDuration("SESSION_TTL", 24*time.Hour),
+		
+		OIDCIssuer:            strings.TrimSuffix(getEnv("OIDC_ISSUER", ""), "/"),
+		OIDCClientID:          getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:       getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:            getEnvListDefault("OIDC_SCOPES", "openid,email,profile"),
+		OIDCGroupsClaim:       getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:       getEnvMap("OIDC_ROLE_MAPPING"),
+		OIDCPostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", ""),
+		
+		MailDriver:    getEnv("MAIL_DRIVER", "file"),
+		MailFrom:      getEnv("MAIL_FROM", "topService <no-reply@topservice.local>"),
+		MailOutboxDir: getEnv("MAIL_OUTBOX_DIR", "outbox"),
+		SMTPHost:      getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:      getEnv("SMTP_PORT", "587"),
+		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+		
+		EmailVerifyURL:        getEnv("EMAIL_VERIFY_URL", "http://localhost:8080/api/v1/auth/email/verify"),
+		PasswordResetURL:      getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		EmailChangeURL:        getEnv("EMAIL_CHANGE_URL", "http://localhost:8080/api/v1/auth/email/change/confirm"),
+		EmailVerifyTokenTTL:   getEnvDuration("EMAIL_VERIFY_TOKEN_TTL", 24*time.Hour),
+		PasswordResetTokenTTL: getEnvDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+		RequireVerifiedEmail:  getEnv("REQUIRE_VERIFIED_EMAIL", "false") == "true",
+		
+		TOTPIssuer:             getEnv("TOTP_ISSUER", "topService"),
+		TwoFactorChallengeTTL:  getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		TwoFactorRequiredRoles: getEnvListDefault("TWO_FACTOR_REQUIRED_ROLES", "admin"),
+		
+		LoginMaxFailures:     int(getEnvInt64("LOGIN_MAX_FAILURES", 5)),
+		LoginIPMaxFailures:   int(getEnvInt64("LOGIN_IP_MAX_FAILURES", 20)),
+		LoginLockoutDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginDelayBase:       getEnvDuration("LOGIN_DELAY_BASE", time.Second),
+		LoginFailureWindow:   getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		
+		EventRetention:          getEnvDuration("EVENT_RETENTION", 7*24*time.Hour),
+		WebhookDispatchInterval: getEnvDuration("WEBHOOK_DISPATCH_INTERVAL", time.Second),
+		WebhookTimeout:          getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:      int(getEnvInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		WebhookBackoffBase:      getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:       getEnvDuration("WEBHOOK_BACKOFF_MAX", 6*time.Hour),
+		WebhookConcurrency:      int(getEnvInt64("WEBHOOK_CONCURRENCY", 8)),
+		
+		EventStreamPollInterval: getEnvDuration("EVENT_STREAM_POLL_INTERVAL", time.Second),
+		EventStreamHeartbeat:    getEnvDuration("EVENT_STREAM_HEARTBEAT", 15*time.Second),
+		EventStreamMaxDuration:  getEnvDuration("EVENT_STREAM_MAX_DURATION", time.Hour),
+		
+		JobWorkers:      int(getEnvInt64("JOB_WORKERS", 4)),
+		JobPollInterval: getEnvDuration("JOB_POLL_INTERVAL", time.Second),
+		JobLease:        getEnvDuration("JOB_LEASE", time.Minute),
+		JobMaxAttempts:  int(getEnvInt64("JOB_MAX_ATTEMPTS", 5)),
+		JobBackoffBase:  getEnvDuration("JOB_BACKOFF_BASE", 10*time.Second),
+		JobBackoffMax:   getEnvDuration("JOB_BACKOFF_MAX", time.Hour),
+		JobRetention:    getEnvDuration("JOB_RETENTION", 7*24*time.Hour),
+		ExportDir:       getEnv("EXPORT_DIR", "exports"),
+		
+		VideoCheckTimeout: getEnvDuration("VIDEO_CHECK_TIMEOUT", 10*time.Second),
+		
+		SchedulerPollInterval: getEnvDuration("SCHEDULER_POLL_INTERVAL", 10*time.Second),
+		TaskRunRetention:      getEnvDuration("TASK_RUN_RETENTION", 30*24*time.Hour),
+		MovieStatsSchedule:    getEnv("SCHEDULE_MOVIE_STATS", "*/5 * * * *"),
+		TrashPurgeSchedule:    getEnv("SCHEDULE_TRASH_PURGE", "@hourly"),
+		ExpireTokensSchedule:  getEnv("SCHEDULE_EXPIRE_TOKENS", "@hourly"),
+		VideoCheckSchedule:    getEnv("SCHEDULE_VIDEO_CHECK", "0 3 * * *"),
+		
+		CacheDriver:        getEnv("CACHE_DRIVER", "memory"),
+		CacheSize:          int(getEnvInt64("CACHE_SIZE", 10000)),
+		RedisAddr:          getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
+		RedisDB:            int(getEnvInt64("REDIS_DB", 0)),
+		RedisKeyPrefix:     getEnv("REDIS_KEY_PREFIX", "topservice:"),
+		MovieListCacheTTL:  getEnvDuration("MOVIE_LIST_CACHE_TTL", 5*time.Minute),
+		MovieStatsCacheTTL: getEnvDuration("MOVIE_STATS_CACHE_TTL", time.Minute),
+		
+		CompressionEnabled: getEnv("COMPRESSION_ENABLED", "true") == "true",
+		CompressionMinSize: int(getEnvInt64("COMPRESSION_MIN_SIZE", 1024)),
+		PublicCacheMaxAge:  getEnvDuration("PUBLIC_CACHE_MAX_AGE", time.Minute),
+		ResponseCacheTTL:   getEnvDuration("RESPONSE_CACHE_TTL", 0),
 	}
 }
 
@@ -52,4 +326,114 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvList 读取逗号分隔的环境变量，忽略空项
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// getEnvListDefault 与 getEnvList 相同，未设置时使用逗号分隔的默认值
+func getEnvListDefault(key, defaultValue string) []string {
+	return splitList(getEnv(key, defaultValue))
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap 读取形如 "a=1,b=2" 的环境变量，忽略格式错误的项
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			log.Printf("Warning: invalid entry for %s: %s", key, item)
+			continue
+		}
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return m
+}
+
+// getEnvInt64 读取整数类型的环境变量，格式错误时使用默认值
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: invalid integer for %s: %s, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDuration 读取时长类型的环境变量（如 30m、24h），格式错误时使用默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration for %s: %s, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvRateLimits 读取形如 "default=300/1m,movie_search=30/1m" 的限流配置
+// 环境变量中的分组覆盖默认值中的同名分组，格式错误的项会被忽略
+func getEnvRateLimits(key, defaultValue string) map[string]RateLimit {
+	limits := make(map[string]RateLimit)
+	for _, raw := range []string{defaultValue, os.Getenv(key)} {
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			
+			limit, err := parseRateLimit(item)
+			if err != nil {
+				log.Printf("Warning: invalid rate limit for %s: %s", key, item)
+				continue
+			}
+			name := strings.TrimSpace(item[:strings.Index(item, "=")])
+			limits[name] = limit
+		}
+	}
+	return limits
+}
+
+// parseRateLimit 解析 "name=次数/时长" 形式的配额
+func parseRateLimit(item string) (RateLimit, error) {
+	parts := strings.SplitN(item, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return RateLimit{}, strconv.ErrSyntax
+	}
+	
+	quota := strings.SplitN(strings.TrimSpace(parts[1]), "/", 2)
+	if len(quota) != 2 {
+		return RateLimit{}, strconv.ErrSyntax
+	}
+	
+	requests, err := strconv.Atoi(quota[0])
+	if err != nil || requests <= 0 {
+		return RateLimit{}, strconv.ErrSyntax
+	}
+	period, err := time.ParseDuration(quota[1])
+	if err != nil || period <= 0 {
+		return RateLimit{}, strconv.ErrSyntax
+	}
+	
+	return RateLimit{Requests: requests, Period: period}, nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 	"topService/internal/config"
 	"topService/internal/model"
 
@@ -13,13 +14,7 @@ import (
 
 func Initialize(cfg *config.Config) (*gorm.DB, error) {
 	// 构建数据库连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=10s&readTimeout=30s&writeTimeout=30s",
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
-	)
+	dsn := buildDSN(cfg, cfg.DBHost+":"+cfg.DBPort)
 	
 	// 配置GORM日志级别
 	var logLevel logger.LogLevel
@@ -38,6 +33,11 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	
+	// 注册只读副本，读操作路由到副本，写操作保留在主库
+	if err := registerReplicas(db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to register replicas: %w", err)
+	}
+	
 	// 获取底层的sql.DB对象进行连接池配置
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -52,11 +52,134 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
+// buildDSN 根据地址构建MySQL连接字符串，主库与副本共用账号和库名
+func buildDSN(cfg *config.Config, addr string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=10s&readTimeout=30s&writeTimeout=30s",
+		cfg.DBUser,
+		cfg.DBPassword,
+		addr,
+		cfg.DBName,
+	)
+}
+
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// 旧版用户名/邮箱唯一索引不区分软删除，替换为包含 deleted_id 的联合唯一索引
+	if err := dropIndexes(db, &model.User{}, "idx_users_username", "idx_users_email"); err != nil {
+		return err
+	}
+	
+	if err := db.AutoMigrate(
 		&model.User{},
 		&model.Product{},
+		&model.IdempotencyRecord{},
+		&model.APIKey{},
+		&model.UserIdentity{},
+		&model.UserToken{},
+		&model.RecoveryCode{},
+		&model.Session{},
+		&model.LoginThrottle{},
+		&model.AuditLog{},
+		&model.MovieRevision{},
+		&model.OutboxEvent{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.Job{},
+		&model.MovieVideoCheck{},
+		&model.MovieStats{},
+		&model.ScheduledTask{},
+		&model.TaskRun{},
 		// Movie表已存在，不需要自动迁移
 		// &model.Movie{},
-	)
+	); err != nil {
+		return err
+	}
+	
+	// 已软删除的历史用户补齐 deleted_id
+	if err := db.Model(&model.User{}).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_id = 0").
+		Update("deleted_id", gorm.Expr("id")).Error; err != nil {
+		return err
+	}
+	
+	// Movie表只补充新增的列，不改动已有列
+	if err := addMissingColumns(db, &model.Movie{}, "Version", "DeletedAt"); err != nil {
+		return err
+	}
+	if !db.Migrator().HasIndex(&model.Movie{}, "DeletedAt") {
+		if err := db.Migrator().CreateIndex(&model.Movie{}, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+	
+	// 审计日志只允许追加，数据库层用触发器拒绝修改和删除；没有创建触发器的权限时只依赖应用层校验
+	if err := createAuditTriggers(db); err != nil {
+		log.Printf("Warning: failed to create audit log triggers, audit_logs is only protected by the application: %v", err)
+	}
+	return nil
+}
+
+// createAuditTriggers 创建拒绝修改、删除 audit_logs 的触发器
+func createAuditTriggers(db *gorm.DB) error {
+	for _, event := range []string{"UPDATE", "DELETE"} {
+		name := "audit_logs_no_" + strings.ToLower(event)
+		var statements []string
+		switch db.Dialector.Name() {
+		case "mysql":
+			statements = []string{
+				"DROP TRIGGER IF EXISTS " + name,
+				"CREATE TRIGGER " + name + " BEFORE " + event + " ON audit_logs FOR EACH ROW " +
+					"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'",
+			}
+		case "sqlite":
+			statements = []string{
+				"CREATE TRIGGER IF NOT EXISTS " + name + " BEFORE " + event + " ON audit_logs " +
+					"BEGIN SELECT RAISE(ABORT, 'audit_logs is append-only'); END",
+			}
+		default:
+			return fmt.Errorf("unsupported dialect %s", db.Dialector.Name())
+		}
+		
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dropIndexes 删除已存在的索引，表或索引不存在时忽略
+func dropIndexes(db *gorm.DB, value interface{}, names ...string) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(value) {
+		return nil
+	}
+	
+	for _, name := range names {
+		if !migrator.HasIndex(value, name) {
+			continue
+		}
+		if err := migrator.DropIndex(value, name); err != nil {
+			return fmt.Errorf("failed to drop index %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// addMissingColumns 为已存在的表补充缺失的列，表不存在时直接建表
+func addMissingColumns(db *gorm.DB, value interface{}, fields ...string) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(value) {
+		return migrator.CreateTable(value)
+	}
+	
+	for _, field := range fields {
+		if migrator.HasColumn(value, field) {
+			continue
+		}
+		if err := migrator.AddColumn(value, field); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", field, err)
+		}
+	}
+	return nil
 }
\ No newline at end of file
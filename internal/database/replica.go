@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"topService/internal/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// RoundRobinPolicy 轮询选择副本
+type RoundRobinPolicy struct {
+	next uint64
+}
+
+func (p *RoundRobinPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	n := atomic.AddUint64(&p.next, 1)
+	return connPools[(n-1)%uint64(len(connPools))]
+}
+
+// newReplicaPolicy 根据配置名称创建副本选择策略
+func newReplicaPolicy(name string) (dbresolver.Policy, error) {
+	switch name {
+	case "", "random":
+		return dbresolver.RandomPolicy{}, nil
+	case "round_robin":
+		return &RoundRobinPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown replica policy: %s", name)
+	}
+}
+
+// registerReplicas 为主库注册只读副本
+// 未配置副本时不做任何处理，所有查询仍走主库
+func registerReplicas(db *gorm.DB, cfg *config.Config) error {
+	if len(cfg.DBReplicaHosts) == 0 {
+		return nil
+	}
+	
+	policy, err := newReplicaPolicy(cfg.DBReplicaPolicy)
+	if err != nil {
+		return err
+	}
+	
+	replicas := make([]gorm.Dialector, len(cfg.DBReplicaHosts))
+	for i, addr := range cfg.DBReplicaHosts {
+		replicas[i] = mysql.Open(buildDSN(cfg, addr))
+	}
+	
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   policy,
+	}).
+		SetMaxIdleConns(10).
+		SetMaxOpenConns(100)
+	
+	if err := db.Use(resolver); err != nil {
+		return err
+	}
+	
+	log.Printf("Database replicas registered: %d (%s)", len(replicas), cfg.DBReplicaPolicy)
+	return nil
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// Transaction 在事务中执行 fn，事务通过 ctx 向下传递给各个服务
+// fn 返回错误或发生 panic 时自动回滚；ctx 中已存在事务时以保存点方式嵌套
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		db = tx
+	} else {
+		db = db.WithContext(ctx)
+	}
+	
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// Conn 返回 ctx 中的事务连接，不在事务中时返回绑定 ctx 的普通连接
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey 创建 API Key，明文密钥只在响应中出现一次
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req model.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	apiKey, key, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), &req, middleware.CallerID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "创建API Key失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	response := apiKey.ToResponse()
+	response.Key = key
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API Key创建成功，请妥善保存，密钥不会再次显示",
+		"data":    response,
+	})
+}
+
+// GetAPIKeys 获取 API Key 列表
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	includeRevoked := c.Query("include_revoked") == "true"
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	apiKeys, total, err := h.apiKeyService.GetAPIKeys(c.Request.Context(), page, pageSize, includeRevoked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取API Key列表失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	responses := make([]*model.APIKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		responses[i] = apiKey.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetAPIKey 获取单个 API Key
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的API Key ID",
+		})
+		return
+	}
+	
+	apiKey, err := h.apiKeyService.GetAPIKeyByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": apiKey.ToResponse(),
+	})
+}
+
+// RevokeAPIKey 吊销 API Key
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的API Key ID",
+		})
+		return
+	}
+	
+	apiKey, err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API Key已吊销",
+		"data":    apiKey.ToResponse(),
+	})
+}
+
+// RotateAPIKey 轮换 API Key，?grace=1h 时旧密钥在宽限期内仍然可用
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的API Key ID",
+		})
+		return
+	}
+	
+	var grace time.Duration
+	if raw := c.Query("grace"); raw != "" {
+		if grace, err = time.ParseDuration(raw); err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "grace 格式错误，例如 30m、24h",
+			})
+			return
+		}
+	}
+	
+	apiKey, key, err := h.apiKeyService.RotateAPIKey(c.Request.Context(), uint(id), grace, middleware.CallerID(c))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	response := apiKey.ToResponse()
+	response.Key = key
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API Key已轮换，请妥善保存新密钥",
+		"data":    response,
+	})
+}
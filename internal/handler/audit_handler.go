@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditLogs 查询审计日志（管理员）
+// 可按 entity_type、entity_id、actor、action、request_id 筛选，from / to 为 RFC 3339 时间，区间左闭右开
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	filter := &model.AuditLogFilter{
+		EntityType: c.Query("entity_type"),
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		RequestID:  c.Query("request_id"),
+	}
+	if value := c.Query("entity_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的entity_id",
+			})
+			return
+		}
+		filter.EntityID = uint(id)
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的" + name + "时间，应为RFC 3339格式",
+			})
+			return
+		}
+		*target = &t
+	}
+	
+	logs, total, err := h.auditService.GetAuditLogs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取审计日志失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	responses := make([]*model.AuditLogResponse, len(logs))
+	for i, entry := range logs {
+		responses[i] = entry.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
@@ -0,0 +1,815 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"topService/internal/mailer"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// oidcStateCookie 保存 OIDC 登录状态的 Cookie
+	oidcStateCookie = "topservice_oidc_state"
+	// oidcCookiePath Cookie 只在 OIDC 回调路径下发送
+	oidcCookiePath = "/api/v1/auth/oidc"
+)
+
+type AuthHandler struct {
+	accountService    *service.AccountService
+	oidcService       *service.OIDCService
+	sessionService    *service.SessionService
+	twoFactorService  *service.TwoFactorService
+	throttleService   *service.LoginThrottleService
+	userService       *service.UserService
+	postLoginRedirect string
+}
+
+func NewAuthHandler(accountService *service.AccountService, oidcService *service.OIDCService, sessionService *service.SessionService, twoFactorService *service.TwoFactorService, throttleService *service.LoginThrottleService, userService *service.UserService, postLoginRedirect string) *AuthHandler {
+	return &AuthHandler{
+		accountService:    accountService,
+		oidcService:       oidcService,
+		sessionService:    sessionService,
+		twoFactorService:  twoFactorService,
+		throttleService:   throttleService,
+		userService:       userService,
+		postLoginRedirect: postLoginRedirect,
+	}
+}
+
+// setStateCookie 写入或清除登录状态 Cookie
+func setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// requestLocale 邮件语言，优先使用请求体中的 locale，否则按 Accept-Language
+func requestLocale(c *gin.Context, locale string) string {
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+	return mailer.NormalizeLocale(locale)
+}
+
+// issueSession 签发会话令牌并返回，twoFactor 表示本次登录是否通过了两步验证
+func (h *AuthHandler) issueSession(c *gin.Context, user *model.User, twoFactor bool) {
+	token, expiresAt, err := h.sessionService.IssueToken(c.Request.Context(), user, twoFactor, sessionMeta(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "签发会话失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "登录成功",
+		"data": gin.H{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_at":   expiresAt,
+			"user":         user.ToResponse(),
+		},
+	})
+}
+
+// sessionMeta 会话记录的客户端信息
+func sessionMeta(c *gin.Context) model.SessionMeta {
+	return model.SessionMeta{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// writeLoginThrottled 登录失败次数过多时返回 429 和 Retry-After，返回是否已写入响应
+func writeLoginThrottled(c *gin.Context, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	
+	c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(throttled.RetryAfter.Seconds())), 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":  throttled.Error(),
+		"locked": throttled.Locked,
+	})
+	return true
+}
+
+// completeLogin 第一步登录通过后，启用了两步验证的用户返回第二步令牌，否则直接签发会话
+func (h *AuthHandler) completeLogin(c *gin.Context, user *model.User) {
+	if !user.TOTPEnabled() {
+		h.issueSession(c, user, false)
+		return
+	}
+	
+	challenge, expiresAt, err := h.twoFactorService.Challenge(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "签发会话失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请输入两步验证码",
+		"data": gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_at":          expiresAt,
+		},
+	})
+}
+
+// writeTwoFactorError 按两步验证错误类型返回状态码
+func writeTwoFactorError(c *gin.Context, err error) {
+	if writeLoginThrottled(c, err) {
+		return
+	}
+	
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode), errors.Is(err, service.ErrInvalidTwoFactorChallenge):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrUserDisabled):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// currentUser 返回当前登录用户，调用者不是登录用户时返回 401
+func currentUser(c *gin.Context) (*model.Principal, bool) {
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil || principal.Type != model.PrincipalUser {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "需要用户登录",
+		})
+		return nil, false
+	}
+	return principal, true
+}
+
+// bindCode 读取请求中的动态码或恢复码
+func bindCode(c *gin.Context) (string, bool) {
+	var req model.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误",
+			"details": err.Error(),
+		})
+		return "", false
+	}
+	return req.Code, true
+}
+
+// TwoFactorLogin 登录第二步：提交第一步返回的令牌和动态码（或恢复码）
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req model.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	user, err := h.twoFactorService.VerifyChallenge(c.Request.Context(), req.ChallengeToken, req.Code, c.ClientIP())
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	h.issueSession(c, user, true)
+}
+
+// EnrollTwoFactor 开始绑定验证器，返回密钥和 otpauth 地址
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	
+	resp, err := h.twoFactorService.Enroll(c.Request.Context(), principal.ID)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请用验证器应用扫描二维码，并提交生成的动态码完成绑定",
+		"data":    resp,
+	})
+}
+
+// ConfirmTwoFactor 提交动态码完成绑定，返回恢复码和已通过两步验证的新会话令牌
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	
+	codes, err := h.twoFactorService.Confirm(c.Request.Context(), principal.ID, code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	user, err := h.userService.GetUserByID(c.Request.Context(), principal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	token, expiresAt, err := h.sessionService.IssueToken(c.Request.Context(), user, true, sessionMeta(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "签发会话失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已启用，请妥善保存恢复码，它们只显示这一次",
+		"data": gin.H{
+			"recovery_codes": codes,
+			"access_token":   token,
+			"token_type":     "Bearer",
+			"expires_at":     expiresAt,
+		},
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码作废
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), principal.ID, code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "恢复码已重新生成",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor 提交动态码或恢复码后关闭两步验证
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	
+	if err := h.twoFactorService.Disable(c.Request.Context(), principal.ID, code); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已关闭",
+	})
+}
+
+// ResetTwoFactor 管理员为丢失验证器的用户关闭两步验证
+func (h *AuthHandler) ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	if err := h.twoFactorService.Reset(c.Request.Context(), uint(id)); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已重置该用户的两步验证",
+	})
+}
+
+// Login 用户名或邮箱 + 密码登录
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req model.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	user, err := h.accountService.Login(c.Request.Context(), req.Login, req.Password, c.ClientIP())
+	if writeLoginThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidLogin):
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrUserDisabled):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	h.completeLogin(c, user)
+}
+
+// SendVerificationEmail 向当前登录用户的邮箱发送验证邮件
+func (h *AuthHandler) SendVerificationEmail(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	
+	var req model.EmailVerificationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	sent, err := h.accountService.SendVerificationEmail(c.Request.Context(), principal.ID, requestLocale(c, req.Locale))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "发送验证邮件失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !sent {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "邮箱已验证",
+		})
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "验证邮件将很快送达",
+	})
+}
+
+// VerifyEmail 使用邮件中的令牌验证邮箱，GET 用于邮件链接直接打开，POST 用于前端提交
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req model.EmailVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+				"details": err.Error(),
+			})
+			return
+		}
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "缺少令牌",
+		})
+		return
+	}
+	
+	user, err := h.accountService.VerifyEmail(c.Request.Context(), token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidUserToken) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮箱验证成功",
+		"data":    user.ToResponse(),
+	})
+}
+
+// ConfirmEmailChange 使用发往新邮箱的令牌确认修改邮箱，GET 用于邮件链接直接打开，POST 用于前端提交
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req model.EmailVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+				"details": err.Error(),
+			})
+			return
+		}
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "缺少令牌",
+		})
+		return
+	}
+	
+	user, err := h.accountService.ConfirmEmailChange(c.Request.Context(), token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidUserToken):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrEmailTaken):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮箱修改成功",
+		"data":    user.ToResponse(),
+	})
+}
+
+// ForgotPassword 发送密码重置邮件，无论邮箱是否存在都返回 202
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req model.PasswordForgotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if err := h.accountService.RequestPasswordReset(c.Request.Context(), req.Email, requestLocale(c, req.Locale)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "发送重置邮件失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "如果该邮箱已注册并验证，重置邮件将很快送达",
+	})
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req model.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if _, err := h.accountService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidUserToken):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrUserDisabled):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已重置",
+	})
+}
+
+// OIDCLogin 跳转到身份提供方登录页
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if !h.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "未启用OIDC登录",
+		})
+		return
+	}
+	
+	authURL, stateToken, err := h.oidcService.StartLogin(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "无法连接身份提供方",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	setStateCookie(c, stateToken, int((10 * time.Minute).Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调，登录成功后签发本服务的会话令牌
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if !h.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "未启用OIDC登录",
+		})
+		return
+	}
+	
+	if idpError := c.Query("error"); idpError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "身份提供方拒绝登录",
+			"details": idpError + " " + c.Query("error_description"),
+		})
+		return
+	}
+	
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	setStateCookie(c, "", -1)
+	
+	user, err := h.oidcService.FinishLogin(c.Request.Context(), c.Query("code"), c.Query("state"), stateToken)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, service.ErrUserDisabled) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": "OIDC登录失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if h.postLoginRedirect == "" {
+		h.completeLogin(c, user)
+		return
+	}
+	
+	// 配置了前端地址时通过 URL 片段传递令牌，片段不会发送到服务端日志
+	var fragment url.Values
+	if user.TOTPEnabled() {
+		challenge, expiresAt, err := h.twoFactorService.Challenge(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "签发会话失败",
+				"details": err.Error(),
+			})
+			return
+		}
+		fragment = url.Values{
+			"two_factor_required": {"true"},
+			"challenge_token":     {challenge},
+			"expires_at":          {strconv.FormatInt(expiresAt.Unix(), 10)},
+		}
+	} else {
+		token, expiresAt, err := h.sessionService.IssueToken(c.Request.Context(), user, false, sessionMeta(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "签发会话失败",
+				"details": err.Error(),
+			})
+			return
+		}
+		fragment = url.Values{
+			"access_token": {token},
+			"token_type":   {"Bearer"},
+			"expires_at":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		}
+	}
+	c.Redirect(http.StatusFound, h.postLoginRedirect+"#"+fragment.Encode())
+}
+
+// Me 返回当前调用者，登录用户同时返回用户信息
+func (h *AuthHandler) Me(c *gin.Context) {
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "需要认证",
+		})
+		return
+	}
+	
+	data := gin.H{"principal": principal}
+	if principal.Type == model.PrincipalUser {
+		user, err := h.userService.GetUserByID(c.Request.Context(), principal.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		data["user"] = user.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
+
+// GetSessions 当前用户已登录的设备
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	
+	h.writeSessions(c, principal.ID, principal.SessionID)
+}
+
+// RevokeSession 退出当前用户的某个设备
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的会话ID",
+		})
+		return
+	}
+	
+	if err := h.sessionService.RevokeSession(c.Request.Context(), principal.ID, uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "会话已吊销",
+	})
+}
+
+// RevokeSessions 退出当前用户的全部设备，keep_current=true 时保留当前会话
+func (h *AuthHandler) RevokeSessions(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	
+	var exceptID uint
+	if c.Query("keep_current") == "true" {
+		exceptID = principal.SessionID
+	}
+	h.revokeSessions(c, principal.ID, exceptID)
+}
+
+// Logout 吊销当前会话
+func (h *AuthHandler) Logout(c *gin.Context) {
+	principal, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	
+	if err := h.sessionService.RevokeSession(c.Request.Context(), principal.ID, principal.SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已退出登录",
+	})
+}
+
+// GetUserSessions 管理员查看用户已登录的设备
+func (h *AuthHandler) GetUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	var currentID uint
+	if principal := middleware.CurrentPrincipal(c); principal != nil && principal.Type == model.PrincipalUser {
+		currentID = principal.SessionID
+	}
+	h.writeSessions(c, uint(id), currentID)
+}
+
+// RevokeUserSessions 管理员让用户在全部设备上退出登录
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	h.revokeSessions(c, uint(id), 0)
+}
+
+// UnlockUser 管理员解除用户因登录失败过多导致的锁定
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	if err := h.throttleService.Unlock(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已解除锁定",
+	})
+}
+
+func (h *AuthHandler) writeSessions(c *gin.Context, userID, currentID uint) {
+	sessions, err := h.sessionService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取会话失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	data := make([]*model.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		data[i] = session.ToResponse(currentID)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
+
+func (h *AuthHandler) revokeSessions(c *gin.Context, userID, exceptID uint) {
+	revoked, err := h.sessionService.RevokeSessions(c.Request.Context(), userID, exceptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "会话已吊销",
+		"data": gin.H{
+			"revoked": revoked,
+		},
+	})
+}
\ No newline at end of file
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBatchSize 单次批量操作允许的最大条目数
+const maxBatchSize = 1000
+
+// 批量操作中单个条目的状态
+const (
+	batchStatusOK         = "ok"
+	batchStatusInvalid    = "invalid"     // 未通过参数校验
+	batchStatusFailed     = "failed"      // 执行失败
+	batchStatusRolledBack = "rolled_back" // 自身成功但随整体事务回滚
+	batchStatusSkipped    = "skipped"     // 因其他条目校验失败未执行
+)
+
+// batchItemResult 批量操作中单个条目的结果
+type batchItemResult struct {
+	Index  int    `json:"index"`
+	ID     uint   `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchResult 汇总批量操作的逐条结果
+type batchResult struct {
+	atomic  bool
+	results []batchItemResult
+}
+
+// bindBatch 解析批量请求
+// 请求体为 JSON 数组，?mode=atomic（默认）全部成功才提交，?mode=best_effort 跳过失败条目
+func bindBatch(c *gin.Context, items interface{}) (*batchResult, error) {
+	var atomic bool
+	switch c.DefaultQuery("mode", "atomic") {
+	case "atomic":
+		atomic = true
+	case "best_effort":
+		atomic = false
+	default:
+		return nil, errors.New("mode 只能为 atomic 或 best_effort")
+	}
+	
+	// 只解码不校验，校验由 validate 逐条完成
+	if err := decodeJSON(c.Request.Body, items); err != nil {
+		return nil, err
+	}
+	
+	n := reflect.ValueOf(items).Elem().Len()
+	if n == 0 {
+		return nil, errors.New("批量请求不能为空")
+	}
+	if n > maxBatchSize {
+		return nil, fmt.Errorf("单次最多处理 %d 条", maxBatchSize)
+	}
+	
+	results := make([]batchItemResult, n)
+	for i := range results {
+		results[i] = batchItemResult{Index: i, Status: batchStatusOK}
+	}
+	
+	return &batchResult{atomic: atomic, results: results}, nil
+}
+
+// validate 按绑定规则逐条校验 items（结构体指针切片），返回通过校验的条目下标
+// atomic 模式下只要有条目未通过校验，其余条目全部跳过，返回空列表
+func (b *batchResult) validate(items interface{}) []int {
+	list := reflect.ValueOf(items)
+	valid := make([]int, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		// 数组中的 null 解码为 nil 指针，不能交给校验器和服务层
+		if list.Index(i).IsNil() {
+			b.results[i].Status = batchStatusInvalid
+			b.results[i].Error = "条目不能为 null"
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(list.Index(i).Interface()); err != nil {
+			b.results[i].Status = batchStatusInvalid
+			b.results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, i)
+	}
+	
+	if b.atomic && len(valid) < list.Len() {
+		for _, i := range valid {
+			b.results[i].Status = batchStatusSkipped
+		}
+		return nil
+	}
+	
+	return valid
+}
+
+// record 记录执行结果，errs 与 valid 一一对应，id 返回第 k 个执行条目的ID
+func (b *batchResult) record(valid []int, errs []error, id func(k int) uint) {
+	for k, i := range valid {
+		switch err := errs[k]; {
+		case err == nil:
+			b.results[i].ID = id(k)
+		case errors.Is(err, service.ErrBatchRolledBack):
+			b.results[i].Status = batchStatusRolledBack
+		default:
+			b.results[i].Status = batchStatusFailed
+			b.results[i].Error = err.Error()
+		}
+	}
+}
+
+// write 输出批量结果：全部成功返回 successStatus，部分成功返回 207，全部失败返回 422
+func (b *batchResult) write(c *gin.Context, successStatus int) {
+	succeeded := 0
+	for _, result := range b.results {
+		if result.Status == batchStatusOK {
+			succeeded++
+		}
+	}
+	
+	status := successStatus
+	switch {
+	case succeeded == 0:
+		status = http.StatusUnprocessableEntity
+	case succeeded < len(b.results):
+		status = http.StatusMultiStatus
+	}
+	
+	mode := "best_effort"
+	if b.atomic {
+		mode = "atomic"
+	}
+	
+	c.JSON(status, gin.H{
+		"message": "批量操作完成",
+		"data": gin.H{
+			"mode":      mode,
+			"total":     len(b.results),
+			"succeeded": succeeded,
+			"failed":    len(b.results) - succeeded,
+			"results":   b.results,
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchResponse 批量接口的响应体
+type batchResponse struct {
+	Data struct {
+		Results []batchItemResult `json:"results"`
+	} `json:"data"`
+}
+
+func TestBatchRejectsNullItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 校验失败的条目不会交给服务层，无需真实的服务
+	h := NewMovieHandler(nil, nil)
+	
+	tests := []struct {
+		name     string
+		handler  gin.HandlerFunc
+		body     string
+		statuses []string
+	}{
+		{"create", h.CreateMovies, `[null]`, []string{batchStatusInvalid}},
+		{"update", h.UpdateMovies, `[null]`, []string{batchStatusInvalid}},
+		{"delete", h.DeleteMovies, `[null]`, []string{batchStatusInvalid}},
+		{"atomic skips the rest", h.CreateMovies, `[{"title":"Alien"},null]`, []string{batchStatusSkipped, batchStatusInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/movies/batch", strings.NewReader(tt.body))
+			
+			tt.handler(c)
+			
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusUnprocessableEntity, w.Body)
+			}
+			var resp batchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data.Results) != len(tt.statuses) {
+				t.Fatalf("got %d results, want %d", len(resp.Data.Results), len(tt.statuses))
+			}
+			for i, result := range resp.Data.Results {
+				if result.Status != tt.statuses[i] {
+					t.Errorf("results[%d].status = %q, want %q", i, result.Status, tt.statuses[i])
+				}
+			}
+		})
+	}
+}
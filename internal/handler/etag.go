@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag 根据版本号生成强校验ETag
+func etag(version uint) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// baseETag 去掉压缩中间件为压缩后的响应追加的编码后缀，如 "3-gzip" -> "3"
+func baseETag(tag string) string {
+	if i := strings.LastIndex(tag, "-"); i > 0 && strings.HasSuffix(tag, "\"") {
+		return tag[:i] + "\""
+	}
+	return tag
+}
+
+// ifMatchVersion 解析 If-Match 头中的版本号
+// 未携带或为 * 时返回 0，表示不做版本校验
+func ifMatchVersion(c *gin.Context) (uint, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	
+	// If-Match 只允许强校验，且一次只能指定一个版本
+	if strings.HasPrefix(header, "W/") || strings.Contains(header, ",") {
+		return 0, errors.New("If-Match 只支持单个强校验ETag")
+	}
+	
+	version, err := strconv.ParseUint(strings.Trim(baseETag(header), "\""), 10, 32)
+	if err != nil || version == 0 {
+		return 0, errors.New("无效的If-Match头")
+	}
+	
+	return uint(version), nil
+}
+
+// notModified 判断 If-None-Match 是否命中当前ETag（弱比较）
+// 命中客户端缓存的压缩响应的 ETag 时，304 响应返回该 ETag
+func notModified(c *gin.Context, tag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+		if baseETag(candidate) == tag {
+			c.Header("ETag", candidate)
+			return true
+		}
+	}
+	
+	return false
+}
+
+// writeVersionConflict 输出乐观锁冲突响应，返回是否已处理
+func writeVersionConflict(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrVersionConflict) {
+		return false
+	}
+	
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": err.Error(),
+	})
+	return true
+}
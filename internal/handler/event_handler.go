@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// sseRetry 建议 SSE 客户端断线后的重连间隔
+	sseRetry = 3 * time.Second
+	
+	// webSocketWriteTimeout WebSocket 单次写入的超时时间，避免卡在失去响应的连接上
+	webSocketWriteTimeout = 10 * time.Second
+)
+
+// eventResourceTypes 权限中的资源名与事件资源类型的对应关系
+var eventResourceTypes = map[string]string{
+	"users":    model.AuditEntityUser,
+	"products": model.AuditEntityProduct,
+	"movies":   model.AuditEntityMovie,
+}
+
+type EventHandler struct {
+	broker      *service.EventBroker
+	heartbeat   time.Duration
+	maxDuration time.Duration
+}
+
+// NewEventHandler heartbeat 为心跳间隔，maxDuration 为单个连接的最长时长，到期后断开由客户端续传以重新认证
+func NewEventHandler(broker *service.EventBroker, heartbeat, maxDuration time.Duration) *EventHandler {
+	return &EventHandler{broker: broker, heartbeat: heartbeat, maxDuration: maxDuration}
+}
+
+// Stream 推送用户、产品、电影的变更事件，默认使用 SSE，请求升级为 WebSocket 时改用 WebSocket
+// ?types=movie,product 按资源类型过滤，只推送调用者有读取权限的资源
+// Last-Event-ID 请求头或 ?last_event_id= 指定最后收到的事件 ID，先补发此后的事件再推送实时事件
+func (h *EventHandler) Stream(c *gin.Context) {
+	resources, ok := streamResources(c)
+	if !ok {
+		return
+	}
+	
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "无效的Last-Event-ID",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	sub, err := h.broker.Subscribe(c.Request.Context(), resources)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrEventBrokerClosed) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"error":   "订阅事件失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+	
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		h.serveWebSocket(c, sub, resources, lastEventID)
+		return
+	}
+	h.serveSSE(c, sub, resources, lastEventID)
+}
+
+// streamResources 按 ?types= 和调用者的读取权限确定推送的资源类型，不合法时输出错误并返回 false
+func streamResources(c *gin.Context) ([]string, bool) {
+	readable := make(map[string]bool)
+	var resources []string
+	for _, resource := range middleware.ReadableResources(c) {
+		readable[eventResourceTypes[resource]] = true
+		resources = append(resources, eventResourceTypes[resource])
+	}
+	
+	types := c.Query("types")
+	if types == "" {
+		return resources, true
+	}
+	
+	known := make(map[string]bool, len(eventResourceTypes))
+	for _, resourceType := range eventResourceTypes {
+		known[resourceType] = true
+	}
+	
+	var selected []string
+	for _, resourceType := range strings.Split(types, ",") {
+		resourceType = strings.TrimSpace(resourceType)
+		if resourceType == "" {
+			continue
+		}
+		if !known[resourceType] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("未知的资源类型: %s", resourceType),
+			})
+			return nil, false
+		}
+		if !readable[resourceType] {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":    "权限不足",
+				"resource": resourceType,
+			})
+			return nil, false
+		}
+		selected = append(selected, resourceType)
+	}
+	
+	if len(selected) == 0 {
+		return resources, true
+	}
+	return selected, true
+}
+
+// parseLastEventID 读取续传的起点，未提供时返回 0
+func parseLastEventID(c *gin.Context) (uint, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	
+	id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// serveSSE 以 text/event-stream 推送事件，id 为事件 ID，event 为事件类型，data 为事件内容
+func (h *EventHandler) serveSSE(c *gin.Context, sub *service.EventSubscription, resources []string, lastEventID uint) {
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// 关闭反向代理（如 Nginx）的响应缓冲
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	w.Flush()
+	
+	send := func(event *model.OutboxEvent) error {
+		data, err := json.Marshal(event.Message())
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}
+	
+	if err := h.pump(c.Request.Context(), sub, resources, lastEventID, send, ping); err != nil {
+		log.Printf("Event stream %s closed: %v", middleware.CallerID(c), err)
+	}
+}
+
+// serveWebSocket 以 WebSocket 文本消息推送事件，每条消息是一个事件的 JSON
+func (h *EventHandler) serveWebSocket(c *gin.Context, sub *service.EventSubscription, resources []string, lastEventID uint) {
+	server := websocket.Server{
+		// 认证只通过请求头完成，不依赖 Cookie，因此不需要校验 Origin 防止跨站劫持
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			
+			// 客户端无需发送消息，读取只用于处理控制帧和发现连接关闭
+			go func() {
+				defer cancel()
+				var message string
+				for {
+					if err := websocket.Message.Receive(ws, &message); err != nil {
+						return
+					}
+				}
+			}()
+			
+			send := func(event *model.OutboxEvent) error {
+				ws.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+				return websocket.JSON.Send(ws, event.Message())
+			}
+			ping := func() error {
+				ws.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+				ws.PayloadType = websocket.PingFrame
+				_, err := ws.Write(nil)
+				ws.PayloadType = websocket.TextFrame
+				return err
+			}
+			
+			if err := h.pump(ctx, sub, resources, lastEventID, send, ping); err != nil {
+				log.Printf("Event stream %s closed: %v", middleware.CallerID(c), err)
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// pump 先补发 lastEventID 之后、订阅开始前的事件，再转发实时事件
+// 直到客户端断开、订阅因消费过慢被断开或超过最长连接时长
+func (h *EventHandler) pump(ctx context.Context, sub *service.EventSubscription, resources []string, lastEventID uint, send func(*model.OutboxEvent) error, ping func() error) error {
+	for after := lastEventID; after > 0 && after < sub.Cursor; {
+		events, err := h.broker.GetEvents(ctx, after, sub.Cursor, resources, 500)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+		for _, event := range events {
+			if err := send(event); err != nil {
+				return err
+			}
+			after = event.ID
+		}
+	}
+	
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	var expired <-chan time.Time
+	if h.maxDuration > 0 {
+		timer := time.NewTimer(h.maxDuration)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-expired:
+			return nil
+		case <-heartbeat:
+			if err := ping(); err != nil {
+				return err
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
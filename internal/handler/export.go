@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"topService/internal/model"
+	"topService/internal/service"
+	"topService/internal/tabular"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportAcceptTypes Accept 头中可协商的导出格式
+var exportAcceptTypes = map[string]tabular.Format{
+	"text/csv":             tabular.FormatCSV,
+	"application/x-ndjson": tabular.FormatJSONL,
+	"application/jsonl":    tabular.FormatJSONL,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": tabular.FormatXLSX,
+}
+
+// exportFormat 确定导出格式：?format= 优先，其次按 Accept 头协商，默认 CSV
+func exportFormat(c *gin.Context) (tabular.Format, error) {
+	if name := c.Query("format"); name != "" {
+		return tabular.ParseFormat(name)
+	}
+	
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if format, ok := exportAcceptTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+	
+	return tabular.FormatCSV, nil
+}
+
+// startExportJob 创建后台导出任务，返回 202 和任务地址；格式与字段的解析方式同同步导出
+func startExportJob(c *gin.Context, exportService *service.ExportService, req *model.ExportRequest) {
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	req.Format = string(format)
+	req.Fields = c.Query("fields")
+	
+	job, err := exportService.StartExport(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidExport) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "创建导出任务失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "导出任务已创建",
+		"data":    jobResponse(job),
+	})
+}
+
+// exportStream 导出过程的状态，负责响应头、分块输出和错误处理
+type exportStream struct {
+	c       *gin.Context
+	writer  tabular.Writer
+	columns *tabular.Columns
+}
+
+// newExportStream 解析导出格式和字段并写出响应头，失败时已输出错误响应并返回 nil
+func newExportStream(c *gin.Context, name string, responseType reflect.Type) *exportStream {
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	
+	columns, err := tabular.SelectColumns(responseType, c.Query("fields"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", tabular.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	
+	writer, err := tabular.NewWriter(c.Writer, format, columns.Names)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	
+	return &exportStream{c: c, writer: writer, columns: columns}
+}
+
+// write 写出一批记录（响应结构体指针）并推送给客户端
+func (e *exportStream) write(responses []interface{}) error {
+	for _, response := range responses {
+		if err := e.writer.WriteRow(e.columns.Values(response)); err != nil {
+			return err
+		}
+	}
+	if err := e.writer.Flush(); err != nil {
+		return err
+	}
+	e.c.Writer.Flush()
+	return nil
+}
+
+// finish 结束导出；中途出错时响应头已发送，只能记录日志并中断输出
+func (e *exportStream) finish(err error) {
+	if err == nil {
+		err = e.writer.Close()
+	}
+	if err != nil {
+		log.Printf("Export %s failed: %v", e.c.Request.URL.Path, err)
+		e.c.Error(err)
+		e.c.Abort()
+	}
+}
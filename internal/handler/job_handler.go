@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hiddenJobTypes 只有管理员可见的任务类型，邮件任务的参数包含收件地址
+var hiddenJobTypes = []string{model.JobSendEmail}
+
+type JobHandler struct {
+	jobService    *service.JobService
+	exportService *service.ExportService
+}
+
+func NewJobHandler(jobService *service.JobService, exportService *service.ExportService) *JobHandler {
+	return &JobHandler{jobService: jobService, exportService: exportService}
+}
+
+// jobID 解析路径中的任务 ID，无效时输出 400 并返回 false
+func jobID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的任务ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeJobError 输出任务操作的错误，不存在时返回 404，状态不允许时返回 409
+func writeJobError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrJobNotFound), os.IsNotExist(err):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrJobNotCancelable), errors.Is(err, service.ErrJobNotRetryable), errors.Is(err, service.ErrExportNotReady):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// isJobAdmin 调用者是否可以查看和管理全部任务
+func isJobAdmin(c *gin.Context) bool {
+	principal := middleware.CurrentPrincipal(c)
+	return principal != nil && principal.HasScope(model.ScopeAdmin)
+}
+
+// jobActor 调用者在任务中记录的标识，与审计日志的操作者相同
+func jobActor(c *gin.Context) string {
+	if principal := middleware.CurrentPrincipal(c); principal != nil {
+		return principal.String()
+	}
+	return model.AuditAnonymous
+}
+
+// jobResponse 转换任务响应，已完成的导出任务附带下载地址
+func jobResponse(job *model.Job) *model.JobResponse {
+	response := job.ToResponse()
+	if job.Type == model.JobExport && job.Status == model.JobStatusSucceeded {
+		response.DownloadURL = fmt.Sprintf("/api/v1/jobs/%d/download", job.ID)
+	}
+	return response
+}
+
+// findJob 查询调用者可见的任务：管理员可见全部任务，其他调用者只能看到自己创建的任务
+// 不可见时与不存在一样返回 404，避免泄露任务是否存在
+func (h *JobHandler) findJob(c *gin.Context) (*model.Job, bool) {
+	id, ok := jobID(c)
+	if !ok {
+		return nil, false
+	}
+	
+	job, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err == nil && !isJobAdmin(c) {
+		hidden := job.Actor != jobActor(c)
+		for _, jobType := range hiddenJobTypes {
+			hidden = hidden || job.Type == jobType
+		}
+		if hidden {
+			err = service.ErrJobNotFound
+		}
+	}
+	if err != nil {
+		writeJobError(c, err, "获取任务失败")
+		return nil, false
+	}
+	return job, true
+}
+
+// GetJobs 获取任务列表，支持 ?type= 和 ?status= 过滤
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	filter := &model.JobFilter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+	}
+	if !isJobAdmin(c) {
+		filter.Actor = jobActor(c)
+		filter.ExcludeTypes = hiddenJobTypes
+	}
+	
+	jobs, total, err := h.jobService.GetJobs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		writeJobError(c, err, "获取任务列表失败")
+		return
+	}
+	
+	responses := make([]*model.JobResponse, len(jobs))
+	for i, job := range jobs {
+		responses[i] = jobResponse(job)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetJob 获取任务状态、进度和结果
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": jobResponse(job),
+	})
+}
+
+// DownloadExport 下载导出任务生成的文件
+func (h *JobHandler) DownloadExport(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+	
+	path, result, err := h.exportService.OpenExport(job)
+	if err != nil {
+		writeJobError(c, err, "下载导出文件失败")
+		return
+	}
+	
+	c.Header("Content-Type", result.ContentType)
+	c.FileAttachment(path, result.Filename)
+}
+
+// CancelJob 取消尚未开始执行的任务
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+	
+	job, err := h.jobService.CancelJob(c.Request.Context(), job.ID)
+	if err != nil {
+		writeJobError(c, err, "取消任务失败")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "任务已取消",
+		"data":    jobResponse(job),
+	})
+}
+
+// RetryJob 重新执行失败或已取消的任务（管理员）
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	
+	job, err := h.jobService.RetryJob(c.Request.Context(), id)
+	if err != nil {
+		writeJobError(c, err, "重试任务失败")
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "任务已重新排队",
+		"data":    jobResponse(job),
+	})
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// decodeJSON 解码 JSON，与 ShouldBindJSON 一致地遵循 binding.EnableDecoderDisallowUnknownFields
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	if binding.EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"reflect"
 	"strconv"
 	"topService/internal/model"
 	"topService/internal/service"
@@ -10,11 +12,12 @@ import (
 )
 
 type MovieHandler struct {
-	movieService *service.MovieService
+	movieService  *service.MovieService
+	exportService *service.ExportService
 }
 
-func NewMovieHandler(movieService *service.MovieService) *MovieHandler {
-	return &MovieHandler{movieService: movieService}
+func NewMovieHandler(movieService *service.MovieService, exportService *service.ExportService) *MovieHandler {
+	return &MovieHandler{movieService: movieService, exportService: exportService}
 }
 
 // CreateMovie 创建电影
@@ -28,7 +31,7 @@ func (h *MovieHandler) CreateMovie(c *gin.Context) {
 		return
 	}
 	
-	movie, err := h.movieService.CreateMovie(&req)
+	movie, err := h.movieService.CreateMovie(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建电影失败",
@@ -37,6 +40,7 @@ func (h *MovieHandler) CreateMovie(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(movie.Version))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "电影创建成功",
 		"data":    movie.ToResponse(),
@@ -54,7 +58,7 @@ func (h *MovieHandler) GetMovie(c *gin.Context) {
 		return
 	}
 	
-	movie, err := h.movieService.GetMovieByID(uint(id))
+	movie, err := h.movieService.GetMovieByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -62,6 +66,14 @@ func (h *MovieHandler) GetMovie(c *gin.Context) {
 		return
 	}
 	
+	// 客户端缓存的版本未变化时直接返回 304
+	tag := etag(movie.Version)
+	c.Header("ETag", tag)
+	if notModified(c, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
 	c.JSON(http.StatusOK, gin.H{
 		"data": movie.ToResponse(),
 	})
@@ -74,6 +86,7 @@ func (h *MovieHandler) GetMovies(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	keyword := c.Query("search")
 	genre := c.Query("genre")
+	includeDeleted := c.Query("include_deleted") == "true"
 	
 	if page < 1 {
 		page = 1
@@ -82,7 +95,7 @@ func (h *MovieHandler) GetMovies(c *gin.Context) {
 		pageSize = 10
 	}
 	
-	movies, total, err := h.movieService.GetMovies(page, pageSize, keyword, genre)
+	movies, total, err := h.movieService.GetMovies(c.Request.Context(), page, pageSize, keyword, genre, includeDeleted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取电影列表失败",
@@ -107,7 +120,43 @@ func (h *MovieHandler) GetMovies(c *gin.Context) {
 	})
 }
 
-// UpdateMovie 更新电影
+// GetDeletedMovies 获取回收站中的电影
+func (h *MovieHandler) GetDeletedMovies(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	movies, total, err := h.movieService.GetDeletedMovies(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取回收站列表失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	movieResponses := make([]*model.MovieResponse, len(movies))
+	for i, movie := range movies {
+		movieResponses[i] = movie.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      movieResponses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// UpdateMovie 全量更新电影
 func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -118,6 +167,14 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		return
 	}
 	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
 	var req model.MovieUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -127,8 +184,57 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		return
 	}
 	
-	movie, err := h.movieService.UpdateMovie(uint(id), &req)
+	movie, err := h.movieService.UpdateMovie(c.Request.Context(), uint(id), version, &req)
+	if err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "更新电影失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "电影更新成功",
+		"data":    movie.ToResponse(),
+	})
+}
+
+// PatchMovie 局部更新电影，支持 JSON Merge Patch 与 JSON Patch
+func (h *MovieHandler) PatchMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	contentType, body, err := readPatch(c)
 	if err != nil {
+		writePatchError(c, err)
+		return
+	}
+	
+	movie, err := h.movieService.PatchMovie(c.Request.Context(), uint(id), version, func(req *model.MovieUpdateRequest) error {
+		return applyPatch(contentType, body, req)
+	})
+	if err != nil {
+		if writeVersionConflict(c, err) || writePatchError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "更新电影失败",
 			"details": err.Error(),
@@ -136,6 +242,7 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(movie.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "电影更新成功",
 		"data":    movie.ToResponse(),
@@ -153,7 +260,18 @@ func (h *MovieHandler) DeleteMovie(c *gin.Context) {
 		return
 	}
 	
-	if err := h.movieService.DeleteMovie(uint(id)); err != nil {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	if err := h.movieService.DeleteMovie(c.Request.Context(), uint(id), version); err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -171,7 +289,7 @@ func (h *MovieHandler) GetMoviesByGenre(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, _ := strconv.Atoi(limitStr)
 	
-	movies, err := h.movieService.GetMoviesByGenre(genre, limit)
+	movies, err := h.movieService.GetMoviesByGenre(c.Request.Context(), genre, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取电影列表失败",
@@ -196,7 +314,7 @@ func (h *MovieHandler) GetTopRatedMovies(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, _ := strconv.Atoi(limitStr)
 	
-	movies, err := h.movieService.GetTopRatedMovies(limit)
+	movies, err := h.movieService.GetTopRatedMovies(c.Request.Context(), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取高评分电影失败",
@@ -218,7 +336,7 @@ func (h *MovieHandler) GetTopRatedMovies(c *gin.Context) {
 
 // GetMovieStats 获取电影统计信息
 func (h *MovieHandler) GetMovieStats(c *gin.Context) {
-	stats, err := h.movieService.GetMovieStats()
+	stats, err := h.movieService.GetMovieStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取统计信息失败",
@@ -230,4 +348,290 @@ func (h *MovieHandler) GetMovieStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"data": stats,
 	})
-}
\ No newline at end of file
+}
+
+// RestoreMovie 从回收站恢复电影
+func (h *MovieHandler) RestoreMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	movie, err := h.movieService.RestoreMovie(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "恢复电影失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "电影恢复成功",
+		"data":    movie.ToResponse(),
+	})
+}
+
+// GetMovieRevisions 获取电影的历史版本
+func (h *MovieHandler) GetMovieRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	revisions, total, err := h.movieService.GetMovieRevisions(c.Request.Context(), uint(id), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	responses := make([]*model.MovieRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		responses[i] = revision.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// DiffMovieRevisions 比较电影的两个历史版本，?from=1&to=3
+func (h *MovieHandler) DiffMovieRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	from, fromErr := strconv.ParseUint(c.Query("from"), 10, 32)
+	to, toErr := strconv.ParseUint(c.Query("to"), 10, 32)
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "from和to必须为版本号",
+		})
+		return
+	}
+	
+	diff, err := h.movieService.DiffMovieRevisions(c.Request.Context(), uint(id), uint(from), uint(to))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": diff,
+	})
+}
+
+// RevertMovie 把电影恢复为指定历史版本的内容，作为新版本保存
+func (h *MovieHandler) RevertMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	revision, err := strconv.ParseUint(c.Param("version"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的版本号",
+		})
+		return
+	}
+	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	movie, err := h.movieService.RevertMovie(c.Request.Context(), uint(id), uint(revision), version)
+	if err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "回滚电影失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "电影已回滚",
+		"data":    movie.ToResponse(),
+	})
+}
+
+// PurgeMovie 彻底删除回收站中的电影（管理员）
+func (h *MovieHandler) PurgeMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的电影ID",
+		})
+		return
+	}
+	
+	if err := h.movieService.PurgeMovie(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "电影已彻底删除",
+	})
+}
+
+// CreateMovies 批量创建电影
+func (h *MovieHandler) CreateMovies(c *gin.Context) {
+	var reqs []*model.MovieCreateRequest
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.MovieCreateRequest, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		movies, errs := h.movieService.CreateMovies(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return movies[k].ID })
+	}
+	
+	batch.write(c, http.StatusCreated)
+}
+
+// UpdateMovies 批量全量更新电影
+func (h *MovieHandler) UpdateMovies(c *gin.Context) {
+	var reqs []*model.MovieBatchUpdateItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.MovieBatchUpdateItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.movieService.UpdateMovies(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// DeleteMovies 批量删除电影
+func (h *MovieHandler) DeleteMovies(c *gin.Context) {
+	var reqs []*model.BatchDeleteItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.BatchDeleteItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.movieService.DeleteMovies(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// ExportMovies 导出电影列表，筛选条件与 GetMovies 一致
+// 支持 ?format=csv|jsonl|xlsx 或 Accept 头协商格式，?fields= 选择导出字段
+// ?async=true 时创建后台导出任务，完成后通过 /api/v1/jobs/:id/download 下载
+func (h *MovieHandler) ExportMovies(c *gin.Context) {
+	keyword := c.Query("search")
+	genre := c.Query("genre")
+	includeDeleted := c.Query("include_deleted") == "true"
+	
+	if c.Query("async") == "true" {
+		startExportJob(c, h.exportService, &model.ExportRequest{Resource: "movies", Keyword: keyword, Genre: genre, IncludeDeleted: includeDeleted})
+		return
+	}
+	
+	stream := newExportStream(c, "movies", reflect.TypeOf(model.MovieResponse{}))
+	if stream == nil {
+		return
+	}
+	
+	err := h.movieService.ExportMovies(c.Request.Context(), keyword, genre, includeDeleted, func(movies []*model.Movie) error {
+		responses := make([]interface{}, len(movies))
+		for i, movie := range movies {
+			responses[i] = movie.ToResponse()
+		}
+		return stream.write(responses)
+	})
+	stream.finish(err)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"topService/internal/service"
+	"topService/internal/tabular"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportFileSize 导入文件大小上限
+	maxImportFileSize = 20 << 20
+	// maxImportRows 导入文件行数上限
+	maxImportRows = 50000
+)
+
+type MovieImportHandler struct {
+	importService *service.MovieImportService
+}
+
+func NewMovieImportHandler(importService *service.MovieImportService) *MovieImportHandler {
+	return &MovieImportHandler{importService: importService}
+}
+
+// ImportMovies 上传 CSV / JSON Lines / XLSX 文件导入电影
+// 表单字段 file 为文件，format 可选（默认按扩展名识别），mapping 可选（JSON 对象，列名 -> 字段名）；
+// ?dry_run=true 只校验不导入，?skip_invalid=true 跳过校验失败的行继续导入
+func (h *MovieImportHandler) ImportMovies(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请上传导入文件",
+			"details": err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "导入文件过大",
+		})
+		return
+	}
+	
+	var format tabular.Format
+	if name := c.PostForm("format"); name != "" {
+		format, err = tabular.ParseFormat(name)
+	} else {
+		format, err = tabular.DetectFormat(fileHeader.Filename)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "mapping 必须是 JSON 对象",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "读取导入文件失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+	
+	records, err := tabular.ReadAll(file, format, service.MovieImportMapping(mapping), maxImportRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "解析导入文件失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	report, rows, err := h.importService.Prepare(c.Request.Context(), records)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "校验导入数据失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "校验完成",
+			"data":    report,
+		})
+		return
+	}
+	
+	if report.Invalid > 0 && c.Query("skip_invalid") != "true" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "存在校验失败的行",
+			"data":  report,
+		})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "没有可导入的数据",
+			"data":  report,
+		})
+		return
+	}
+	
+	job, err := h.importService.StartImport(c.Request.Context(), rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "创建导入任务失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/v1/movies/import/%d", job.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "导入任务已创建",
+		"data": gin.H{
+			"job":    job,
+			"report": report,
+		},
+	})
+}
+
+// GetImportJob 查询导入任务状态，与 /jobs/:id 相同，管理员可见全部任务，其他调用者只能看到自己创建的任务
+func (h *MovieImportHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "导入任务不存在",
+		})
+		return
+	}
+	
+	actor := ""
+	if !isJobAdmin(c) {
+		actor = jobActor(c)
+	}
+	job, err := h.importService.GetJob(c.Request.Context(), uint(id), actor)
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "导入任务不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取导入任务失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": job,
+	})
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	mimeMergePatch = "application/merge-patch+json" // RFC 7396
+	mimeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// patchError 补丁无法应用或应用后未通过校验
+type patchError struct {
+	status int
+	err    error
+}
+
+func (e *patchError) Error() string {
+	return e.err.Error()
+}
+
+// readPatch 读取 PATCH 请求体，并检查 Content-Type 是否受支持
+// application/json 按 JSON Merge Patch 处理
+func readPatch(c *gin.Context) (contentType string, body []byte, err error) {
+	contentType = c.ContentType()
+	switch contentType {
+	case mimeMergePatch, mimeJSONPatch:
+	case binding.MIMEJSON:
+		contentType = mimeMergePatch
+	default:
+		return "", nil, &patchError{
+			status: http.StatusUnsupportedMediaType,
+			err:    errors.New("仅支持 application/merge-patch+json 或 application/json-patch+json"),
+		}
+	}
+	
+	body, err = c.GetRawData()
+	if err != nil {
+		return "", nil, &patchError{status: http.StatusBadRequest, err: err}
+	}
+	
+	return contentType, body, nil
+}
+
+// applyPatch 将补丁应用到 target 指向的请求结构体，并按绑定规则校验结果
+// 补丁中显式的 null 或 remove 操作会把对应字段清空
+func applyPatch(contentType string, body []byte, target interface{}) error {
+	doc, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	
+	var patched []byte
+	if contentType == mimeJSONPatch {
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(body); err == nil {
+			patched, err = ops.Apply(doc)
+		}
+	} else {
+		patched, err = jsonpatch.MergePatch(doc, body)
+	}
+	if err != nil {
+		return &patchError{status: http.StatusBadRequest, err: err}
+	}
+	
+	// 先清零再解码，补丁中被删除的字段才能真正清空
+	value := reflect.ValueOf(target).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if err := decodeJSON(bytes.NewReader(patched), target); err != nil {
+		return &patchError{status: http.StatusBadRequest, err: err}
+	}
+	
+	if err := binding.Validator.ValidateStruct(target); err != nil {
+		return &patchError{status: http.StatusUnprocessableEntity, err: err}
+	}
+	
+	return nil
+}
+
+// writePatchError 输出补丁错误响应，返回是否已处理
+func writePatchError(c *gin.Context, err error) bool {
+	var perr *patchError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	
+	c.JSON(perr.status, gin.H{
+		"error":   "补丁无效",
+		"details": perr.Error(),
+	})
+	return true
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"reflect"
 	"strconv"
 	"topService/internal/model"
 	"topService/internal/service"
@@ -11,10 +12,11 @@ import (
 
 type ProductHandler struct {
 	productService *service.ProductService
+	exportService  *service.ExportService
 }
 
-func NewProductHandler(productService *service.ProductService) *ProductHandler {
-	return &ProductHandler{productService: productService}
+func NewProductHandler(productService *service.ProductService, exportService *service.ExportService) *ProductHandler {
+	return &ProductHandler{productService: productService, exportService: exportService}
 }
 
 // CreateProduct 创建产品
@@ -28,7 +30,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 	
-	product, err := h.productService.CreateProduct(&req)
+	product, err := h.productService.CreateProduct(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建产品失败",
@@ -37,6 +39,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(product.Version))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "产品创建成功",
 		"data":    product.ToResponse(),
@@ -54,7 +57,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 	
-	product, err := h.productService.GetProductByID(uint(id))
+	product, err := h.productService.GetProductByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -62,6 +65,14 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 	
+	// 客户端缓存的版本未变化时直接返回 304
+	tag := etag(product.Version)
+	c.Header("ETag", tag)
+	if notModified(c, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
 	c.JSON(http.StatusOK, gin.H{
 		"data": product.ToResponse(),
 	})
@@ -74,6 +85,7 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 	keyword := c.Query("keyword")
 	category := c.Query("category")
+	includeDeleted := c.Query("include_deleted") == "true"
 	
 	if page < 1 {
 		page = 1
@@ -82,7 +94,7 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		pageSize = 10
 	}
 	
-	products, total, err := h.productService.GetProducts(page, pageSize, keyword, category)
+	products, total, err := h.productService.GetProducts(c.Request.Context(), page, pageSize, keyword, category, includeDeleted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取产品列表失败",
@@ -107,7 +119,43 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 	})
 }
 
-// UpdateProduct 更新产品
+// GetDeletedProducts 获取回收站中的产品
+func (h *ProductHandler) GetDeletedProducts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	products, total, err := h.productService.GetDeletedProducts(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取回收站列表失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	productResponses := make([]*model.ProductResponse, len(products))
+	for i, product := range products {
+		productResponses[i] = product.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      productResponses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// UpdateProduct 全量更新产品
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -118,6 +166,14 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
 	var req model.ProductUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -127,8 +183,11 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 	
-	product, err := h.productService.UpdateProduct(uint(id), &req)
+	product, err := h.productService.UpdateProduct(c.Request.Context(), uint(id), version, &req)
 	if err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "更新产品失败",
 			"details": err.Error(),
@@ -136,6 +195,53 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(product.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "产品更新成功",
+		"data":    product.ToResponse(),
+	})
+}
+
+// PatchProduct 局部更新产品，支持 JSON Merge Patch 与 JSON Patch
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的产品ID",
+		})
+		return
+	}
+	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	contentType, body, err := readPatch(c)
+	if err != nil {
+		writePatchError(c, err)
+		return
+	}
+	
+	product, err := h.productService.PatchProduct(c.Request.Context(), uint(id), version, func(req *model.ProductUpdateRequest) error {
+		return applyPatch(contentType, body, req)
+	})
+	if err != nil {
+		if writeVersionConflict(c, err) || writePatchError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "更新产品失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(product.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "产品更新成功",
 		"data":    product.ToResponse(),
@@ -153,7 +259,18 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 	
-	if err := h.productService.DeleteProduct(uint(id)); err != nil {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	if err := h.productService.DeleteProduct(c.Request.Context(), uint(id), version); err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -163,4 +280,157 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "产品删除成功",
 	})
-}
\ No newline at end of file
+}
+
+// RestoreProduct 从回收站恢复产品
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的产品ID",
+		})
+		return
+	}
+	
+	product, err := h.productService.RestoreProduct(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "恢复产品失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(product.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "产品恢复成功",
+		"data":    product.ToResponse(),
+	})
+}
+
+// PurgeProduct 彻底删除回收站中的产品（管理员）
+func (h *ProductHandler) PurgeProduct(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的产品ID",
+		})
+		return
+	}
+	
+	if err := h.productService.PurgeProduct(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "产品已彻底删除",
+	})
+}
+
+// CreateProducts 批量创建产品
+func (h *ProductHandler) CreateProducts(c *gin.Context) {
+	var reqs []*model.ProductCreateRequest
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.ProductCreateRequest, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		products, errs := h.productService.CreateProducts(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return products[k].ID })
+	}
+	
+	batch.write(c, http.StatusCreated)
+}
+
+// UpdateProducts 批量全量更新产品
+func (h *ProductHandler) UpdateProducts(c *gin.Context) {
+	var reqs []*model.ProductBatchUpdateItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.ProductBatchUpdateItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.productService.UpdateProducts(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// DeleteProducts 批量删除产品
+func (h *ProductHandler) DeleteProducts(c *gin.Context) {
+	var reqs []*model.BatchDeleteItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.BatchDeleteItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.productService.DeleteProducts(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// ExportProducts 导出产品列表，筛选条件与 GetProducts 一致
+// 支持 ?format=csv|jsonl|xlsx 或 Accept 头协商格式，?fields= 选择导出字段
+// ?async=true 时创建后台导出任务，完成后通过 /api/v1/jobs/:id/download 下载
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	keyword := c.Query("keyword")
+	category := c.Query("category")
+	includeDeleted := c.Query("include_deleted") == "true"
+	
+	if c.Query("async") == "true" {
+		startExportJob(c, h.exportService, &model.ExportRequest{Resource: "products", Keyword: keyword, Category: category, IncludeDeleted: includeDeleted})
+		return
+	}
+	
+	stream := newExportStream(c, "products", reflect.TypeOf(model.ProductResponse{}))
+	if stream == nil {
+		return
+	}
+	
+	err := h.productService.ExportProducts(c.Request.Context(), keyword, category, includeDeleted, func(products []*model.Product) error {
+		responses := make([]interface{}, len(products))
+		for i, product := range products {
+			responses[i] = product.ToResponse()
+		}
+		return stream.write(responses)
+	})
+	stream.finish(err)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskHandler struct {
+	scheduler *service.Scheduler
+}
+
+func NewTaskHandler(scheduler *service.Scheduler) *TaskHandler {
+	return &TaskHandler{scheduler: scheduler}
+}
+
+// writeTaskError 输出定时任务操作的错误，不存在时返回 404，正在执行时返回 409
+func writeTaskError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTaskRunning):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// GetTasks 获取定时任务列表，包括 cron 表达式、下次执行时间和最近一次执行（管理员）
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	tasks, err := h.scheduler.GetTasks(c.Request.Context())
+	if err != nil {
+		writeTaskError(c, err, "获取定时任务失败")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": tasks,
+	})
+}
+
+// GetTaskRuns 获取定时任务的执行记录（管理员）
+func (h *TaskHandler) GetTaskRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	runs, total, err := h.scheduler.GetRuns(c.Request.Context(), c.Param("name"), page, pageSize)
+	if err != nil {
+		writeTaskError(c, err, "获取执行记录失败")
+		return
+	}
+	
+	responses := make([]*model.TaskRunResponse, len(runs))
+	for i, run := range runs {
+		responses[i] = run.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// RunTask 立即执行一次定时任务，任务在后台执行，结果见执行记录（管理员）
+func (h *TaskHandler) RunTask(c *gin.Context) {
+	run, err := h.scheduler.Trigger(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeTaskError(c, err, "执行定时任务失败")
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "定时任务已开始执行",
+		"data":    run.ToResponse(),
+	})
+}
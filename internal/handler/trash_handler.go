@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashHandler struct {
+	purgeService *service.PurgeService
+}
+
+func NewTrashHandler(purgeService *service.PurgeService) *TrashHandler {
+	return &TrashHandler{purgeService: purgeService}
+}
+
+// PurgeExpired 立即清理超过保留期的软删除数据（管理员）
+func (h *TrashHandler) PurgeExpired(c *gin.Context) {
+	purged, err := h.purgeService.PurgeExpired(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "清理回收站失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "回收站清理完成",
+		"data":    purged,
+	})
+}
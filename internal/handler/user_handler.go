@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"reflect"
 	"strconv"
 	"topService/internal/model"
 	"topService/internal/service"
@@ -10,11 +12,29 @@ import (
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService   *service.UserService
+	exportService *service.ExportService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *service.UserService, exportService *service.ExportService) *UserHandler {
+	return &UserHandler{userService: userService, exportService: exportService}
+}
+
+// writeEmailChangeError 输出修改邮箱被拒绝的响应，返回是否已处理
+func writeEmailChangeError(c *gin.Context, err error) bool {
+	status := http.StatusForbidden
+	switch {
+	case errors.Is(err, service.ErrEmailChangeForbidden):
+	case errors.Is(err, service.ErrEmailTaken):
+		status = http.StatusConflict
+	default:
+		return false
+	}
+	
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+	return true
 }
 
 // CreateUser 创建用户
@@ -28,7 +48,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 	
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建用户失败",
@@ -37,6 +57,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(user.Version))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "用户创建成功",
 		"data":    user.ToResponse(),
@@ -54,7 +75,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 	
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -62,6 +83,14 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 	
+	// 客户端缓存的版本未变化时直接返回 304
+	tag := etag(user.Version)
+	c.Header("ETag", tag)
+	if notModified(c, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
 	c.JSON(http.StatusOK, gin.H{
 		"data": user.ToResponse(),
 	})
@@ -73,6 +102,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 	keyword := c.Query("keyword")
+	includeDeleted := c.Query("include_deleted") == "true"
 	
 	if page < 1 {
 		page = 1
@@ -81,7 +111,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		pageSize = 10
 	}
 	
-	users, total, err := h.userService.GetUsers(page, pageSize, keyword)
+	users, total, err := h.userService.GetUsers(c.Request.Context(), page, pageSize, keyword, includeDeleted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取用户列表失败",
@@ -106,7 +136,43 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 	})
 }
 
-// UpdateUser 更新用户
+// GetDeletedUsers 获取回收站中的用户
+func (h *UserHandler) GetDeletedUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	users, total, err := h.userService.GetDeletedUsers(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取回收站列表失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	userResponses := make([]*model.UserResponse, len(users))
+	for i, user := range users {
+		userResponses[i] = user.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      userResponses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// UpdateUser 全量更新用户
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -117,6 +183,14 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
 	var req model.UserUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -126,8 +200,57 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 	
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	user, err := h.userService.UpdateUser(c.Request.Context(), uint(id), version, &req)
+	if err != nil {
+		if writeVersionConflict(c, err) || writeEmailChangeError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "更新用户失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户更新成功",
+		"data":    user.ToResponse(),
+	})
+}
+
+// PatchUser 局部更新用户，支持 JSON Merge Patch 与 JSON Patch
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	contentType, body, err := readPatch(c)
+	if err != nil {
+		writePatchError(c, err)
+		return
+	}
+	
+	user, err := h.userService.PatchUser(c.Request.Context(), uint(id), version, func(req *model.UserUpdateRequest) error {
+		return applyPatch(contentType, body, req)
+	})
 	if err != nil {
+		if writeVersionConflict(c, err) || writeEmailChangeError(c, err) || writePatchError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "更新用户失败",
 			"details": err.Error(),
@@ -135,6 +258,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 	
+	c.Header("ETag", etag(user.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "用户更新成功",
 		"data":    user.ToResponse(),
@@ -152,7 +276,18 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 	
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	if err := h.userService.DeleteUser(c.Request.Context(), uint(id), version); err != nil {
+		if writeVersionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -162,4 +297,156 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "用户删除成功",
 	})
-}
\ No newline at end of file
+}
+
+// RestoreUser 从回收站恢复用户
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	user, err := h.userService.RestoreUser(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "恢复用户失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户恢复成功",
+		"data":    user.ToResponse(),
+	})
+}
+
+// PurgeUser 彻底删除回收站中的用户（管理员）
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的用户ID",
+		})
+		return
+	}
+	
+	if err := h.userService.PurgeUser(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户已彻底删除",
+	})
+}
+
+// CreateUsers 批量创建用户
+func (h *UserHandler) CreateUsers(c *gin.Context) {
+	var reqs []*model.UserCreateRequest
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.UserCreateRequest, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		users, errs := h.userService.CreateUsers(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return users[k].ID })
+	}
+	
+	batch.write(c, http.StatusCreated)
+}
+
+// UpdateUsers 批量全量更新用户
+func (h *UserHandler) UpdateUsers(c *gin.Context) {
+	var reqs []*model.UserBatchUpdateItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.UserBatchUpdateItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.userService.UpdateUsers(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// DeleteUsers 批量删除用户
+func (h *UserHandler) DeleteUsers(c *gin.Context) {
+	var reqs []*model.BatchDeleteItem
+	batch, err := bindBatch(c, &reqs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if valid := batch.validate(reqs); len(valid) > 0 {
+		items := make([]*model.BatchDeleteItem, len(valid))
+		for k, i := range valid {
+			items[k] = reqs[i]
+		}
+		
+		errs := h.userService.DeleteUsers(c.Request.Context(), items, batch.atomic)
+		batch.record(valid, errs, func(k int) uint { return items[k].ID })
+	}
+	
+	batch.write(c, http.StatusOK)
+}
+
+// ExportUsers 导出用户列表，筛选条件与 GetUsers 一致
+// 支持 ?format=csv|jsonl|xlsx 或 Accept 头协商格式，?fields= 选择导出字段
+// ?async=true 时创建后台导出任务，完成后通过 /api/v1/jobs/:id/download 下载
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	keyword := c.Query("keyword")
+	includeDeleted := c.Query("include_deleted") == "true"
+	
+	if c.Query("async") == "true" {
+		startExportJob(c, h.exportService, &model.ExportRequest{Resource: "users", Keyword: keyword, IncludeDeleted: includeDeleted})
+		return
+	}
+	
+	stream := newExportStream(c, "users", reflect.TypeOf(model.UserResponse{}))
+	if stream == nil {
+		return
+	}
+	
+	err := h.userService.ExportUsers(c.Request.Context(), keyword, includeDeleted, func(users []*model.User) error {
+		responses := make([]interface{}, len(users))
+		for i, user := range users {
+			responses[i] = user.ToResponse()
+		}
+		return stream.write(responses)
+	})
+	stream.finish(err)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VideoCheckHandler struct {
+	videoCheckService *service.VideoCheckService
+}
+
+func NewVideoCheckHandler(videoCheckService *service.VideoCheckService) *VideoCheckHandler {
+	return &VideoCheckHandler{videoCheckService: videoCheckService}
+}
+
+// StartVideoCheck 创建播放地址检查任务，请求体可选，movie_ids 为空时检查全部有播放地址的电影
+func (h *VideoCheckHandler) StartVideoCheck(c *gin.Context) {
+	var req model.VideoCheckRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "请求参数错误",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	job, err := h.videoCheckService.StartCheck(c.Request.Context(), req.MovieIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "创建检查任务失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "检查任务已创建",
+		"data":    jobResponse(job),
+	})
+}
+
+// GetVideoChecks 获取播放地址检查结果，?status=ok|broken 过滤
+func (h *VideoCheckHandler) GetVideoChecks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	status := c.Query("status")
+	if status != "" && status != model.VideoStatusOK && status != model.VideoStatusBroken {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "status 只能是 ok 或 broken",
+		})
+		return
+	}
+	
+	checks, total, err := h.videoCheckService.GetChecks(c.Request.Context(), status, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取检查结果失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      checks,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// webhookID 解析路径中的 Webhook ID，无效时输出 400 并返回 false
+func webhookID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的Webhook ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeWebhookError 输出 Webhook 操作的错误，不存在时返回 404
+func writeWebhookError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrDeliveryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrDeliveryPending):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// CreateWebhook 创建 Webhook，签名密钥只在响应中出现一次
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req model.WebhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	webhook, secret, err := h.webhookService.CreateWebhook(c.Request.Context(), &req, middleware.CallerID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "创建Webhook失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	response := webhook.ToResponse()
+	response.Secret = secret
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook创建成功，请妥善保存签名密钥，密钥不会再次显示",
+		"data":    response,
+	})
+}
+
+// GetWebhooks 获取 Webhook 列表
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	
+	webhooks, total, err := h.webhookService.GetWebhooks(c.Request.Context(), page, pageSize)
+	if err != nil {
+		writeWebhookError(c, err, "获取Webhook列表失败")
+		return
+	}
+	
+	responses := make([]*model.WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		responses[i] = webhook.ToResponse()
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      responses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetWebhook 获取单个 Webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	
+	webhook, err := h.webhookService.GetWebhookByID(c.Request.Context(), id)
+	if err != nil {
+		writeWebhookError(c, err, "获取Webhook失败")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": webhook.ToResponse(),
+	})
+}
+
+// UpdateWebhook 修改 Webhook，未提供的字段保持不变；active 为 false 时停止投递
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	
+	var req model.WebhookUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			writeWebhookError(c, err, "更新Webhook失败")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "更新Webhook失败",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook更新成功",
+		"data":    webhook.ToResponse(),
+	})
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		writeWebhookError(c, err, "删除Webhook失败")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook删除成功",
+	})
+}
+
+// RotateWebhookSecret 更换签名密钥，新密钥只在响应中出现一次
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	
+	webhook, secret, err := h.webhookService.RotateWebhookSecret(c.Request.Context(), id)
+	if err != nil {
+		writeWebhookError(c, err, "更换签名密钥失败")
+		return
+	}
+	
+	response := webhook.ToResponse()
+	response.Secret = secret
+	c.JSON(http.StatusOK, gin.H{
+		"message": "签名密钥已更换，请妥善保存新密钥",
+		"data":    response,
+	})
+}
+
+// GetDeliveries 获取 Webhook 的投递记录，?status=pending|succeeded|dead 按状态筛选
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	deliveries, total, err := h.webhookService.GetDeliveries(c.Request.Context(), id, c.Query("status"), page, pageSize)
+	if err != nil {
+		writeWebhookError(c, err, "获取投递记录失败")
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"list":      deliveries,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// RetryDelivery 重新投递死信或已完成的投递记录
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的投递记录ID",
+		})
+		return
+	}
+	
+	delivery, err := h.webhookService.RetryDelivery(c.Request.Context(), id, uint(deliveryID))
+	if err != nil {
+		writeWebhookError(c, err, "重新投递失败")
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "已重新加入投递队列",
+		"data":    delivery,
+	})
+}
@@ -0,0 +1,190 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken 令牌格式、算法或签名不正确
+	ErrInvalidToken = errors.New("无效的令牌")
+	// ErrTokenExpired 令牌已过期或尚未生效
+	ErrTokenExpired = errors.New("令牌已过期")
+)
+
+// leeway 校验时间类声明时允许的时钟误差
+const leeway = time.Minute
+
+// Header JWT 头部
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Audience aud 声明，兼容字符串和字符串数组两种写法
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Contains 判断 aud 是否包含指定值
+func (a Audience) Contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredClaims RFC 7519 中的标准声明
+type RegisteredClaims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Validate 校验 exp 与 nbf
+func (c *RegisteredClaims) Validate(now time.Time) error {
+	if c.ExpiresAt != 0 && now.After(time.Unix(c.ExpiresAt, 0).Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// SignHS256 使用 HMAC-SHA256 签发令牌
+func SignHS256(claims interface{}, secret []byte) (string, error) {
+	signingInput, err := encodeSigningInput(Header{Alg: "HS256", Typ: "JWT"}, claims)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignRS256 使用 RSA-SHA256 签发令牌，kid 写入头部供验证方选择公钥
+func SignRS256(claims interface{}, key *rsa.PrivateKey, kid string) (string, error) {
+	signingInput, err := encodeSigningInput(Header{Alg: "RS256", Typ: "JWT", Kid: kid}, claims)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyHS256 校验 HS256 签名并把载荷解码到 claims，不校验时间类声明
+func VerifyHS256(token string, secret []byte, claims interface{}) error {
+	header, signingInput, signature, err := split(token)
+	if err != nil {
+		return err
+	}
+	if header.Alg != "HS256" {
+		return ErrInvalidToken
+	}
+	
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidToken
+	}
+	return decodePayload(signingInput, claims)
+}
+
+// VerifyRS256 校验 RS256 签名并把载荷解码到 claims，key 按头部的 kid 返回公钥
+func VerifyRS256(token string, key func(kid string) (*rsa.PublicKey, error), claims interface{}) error {
+	header, signingInput, signature, err := split(token)
+	if err != nil {
+		return err
+	}
+	if header.Alg != "RS256" {
+		return ErrInvalidToken
+	}
+	
+	publicKey, err := key(header.Kid)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidToken
+	}
+	return decodePayload(signingInput, claims)
+}
+
+func encodeSigningInput(header Header, claims interface{}) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON), nil
+}
+
+// split 拆分令牌为头部、签名输入和签名
+func split(token string) (*Header, string, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", nil, ErrInvalidToken
+	}
+	
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", nil, ErrInvalidToken
+	}
+	var header Header
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, "", nil, ErrInvalidToken
+	}
+	
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", nil, ErrInvalidToken
+	}
+	
+	return &header, parts[0] + "." + parts[1], signature, nil
+}
+
+func decodePayload(signingInput string, claims interface{}) error {
+	payload, err := base64.RawURLEncoding.DecodeString(signingInput[strings.Index(signingInput, ".")+1:])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 支持的语言，未知语言使用 DefaultLocale
+const (
+	LocaleZH      = "zh"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleZH
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// templates 按 "语言/模板名" 索引，每个文件单独解析，避免 subject/body 定义互相覆盖
+var templates = loadTemplates()
+
+func loadTemplates() map[string]*template.Template {
+	files, err := fs.Glob(templateFS, "templates/*/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	
+	loaded := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		key := strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".tmpl")
+		loaded[key] = template.Must(template.ParseFS(templateFS, file))
+	}
+	return loaded
+}
+
+// Message 待发送的邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送方式
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NormalizeLocale 把 Accept-Language 或请求中的语言转换为支持的语言
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	switch {
+	case strings.HasPrefix(locale, LocaleEN):
+		return LocaleEN
+	case strings.HasPrefix(locale, LocaleZH):
+		return LocaleZH
+	default:
+		return DefaultLocale
+	}
+}
+
+// Render 按模板名和语言渲染邮件，模板需定义 subject 和 body 两部分
+func Render(name, locale, to string, data interface{}) (*Message, error) {
+	tmpl, ok := templates[NormalizeLocale(locale)+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("邮件模板不存在: %s", name)
+	}
+	
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, err
+	}
+	
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    strings.TrimSpace(body.String()) + "\n",
+	}, nil
+}
+
+// FormatDuration 把有效期格式化为邮件中展示的文字，如 "24 小时"、"30 minutes"
+func FormatDuration(d time.Duration, locale string) string {
+	zh := NormalizeLocale(locale) == LocaleZH
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if zh {
+			return fmt.Sprintf("%d 小时", d/time.Hour)
+		}
+		return pluralize(int(d/time.Hour), "hour")
+	default:
+		minutes := int((d + time.Minute - 1) / time.Minute)
+		if zh {
+			return fmt.Sprintf("%d 分钟", minutes)
+		}
+		return pluralize(minutes, "minute")
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
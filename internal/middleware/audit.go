@@ -0,0 +1,68 @@
+package middleware
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "regexp"
+    "topService/internal/model"
+    "topService/internal/service"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // requestIDHeader 请求 ID 头，客户端未携带时由服务端生成
+    requestIDHeader = "X-Request-ID"
+    // requestIDContextKey 保存请求 ID 的上下文键
+    requestIDContextKey = "request_id"
+)
+
+// validRequestID 客户端传入的请求 ID 只接受长度有限的常见字符，避免污染日志
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID 为每个请求分配 ID，沿用客户端传入的合法 X-Request-ID，否则随机生成，并在响应头中返回
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(requestIDHeader)
+        if !validRequestID.MatchString(id) {
+            id = newRequestID()
+        }
+        
+        c.Set(requestIDContextKey, id)
+        c.Header(requestIDHeader, id)
+        c.Next()
+    }
+}
+
+// GetRequestID 返回当前请求的 ID，未使用 RequestID 中间件时为空
+func GetRequestID(c *gin.Context) string {
+    return c.GetString(requestIDContextKey)
+}
+
+// newRequestID 生成 32 位十六进制随机 ID
+func newRequestID() string {
+    buf := make([]byte, 16)
+    if _, err := rand.Read(buf); err != nil {
+        return ""
+    }
+    return hex.EncodeToString(buf)
+}
+
+// AuditContext 把调用者、请求 ID 和客户端 IP 写入请求的 ctx，服务层据此记录审计日志
+// 需在 Authenticate 之后使用
+func AuditContext() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        actor := model.AuditAnonymous
+        if principal := CurrentPrincipal(c); principal != nil {
+            actor = principal.String()
+        }
+        
+        ctx := service.WithAuditMeta(c.Request.Context(), model.AuditMeta{
+            Actor:     actor,
+            RequestID: GetRequestID(c),
+            IP:        c.ClientIP(),
+        })
+        c.Request = c.Request.WithContext(ctx)
+        c.Next()
+    }
+}
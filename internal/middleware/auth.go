@@ -0,0 +1,321 @@
+package middleware
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+    "topService/internal/model"
+    "topService/internal/service"
+
+    "github.com/gin-gonic/gin"
+)
+
+// principalContextKey 认证通过后保存调用者的上下文键
+const principalContextKey = "principal"
+
+// readableResourcesKey 保存调用者可读取的资源的上下文键
+const readableResourcesKey = "readable_resources"
+
+// anonymous 不要求认证时的匿名调用者，只用于权限判断，不写入上下文
+var anonymous = &model.Principal{Scopes: model.AnonymousScopes()}
+
+// ErrInvalidCredentials 请求携带了凭证但校验未通过，Authenticator 返回其他错误时按服务端错误处理
+var ErrInvalidCredentials = errors.New("认证凭证无效")
+
+// Authenticator 一种认证方式
+type Authenticator interface {
+    // Authenticate 请求未携带本方式的凭证时返回 nil, nil；凭证无效时返回错误
+    Authenticate(c *gin.Context) (*model.Principal, error)
+}
+
+// CurrentPrincipal 返回已认证的调用者，未认证时返回 nil
+func CurrentPrincipal(c *gin.Context) *model.Principal {
+    if value, ok := c.Get(principalContextKey); ok {
+        return value.(*model.Principal)
+    }
+    return nil
+}
+
+// CallerID 返回请求方标识，未认证时使用客户端 IP
+func CallerID(c *gin.Context) string {
+    if principal := CurrentPrincipal(c); principal != nil {
+        return principal.String()
+    }
+    return "ip:" + c.ClientIP()
+}
+
+// Authenticated 请求是否已通过认证
+func Authenticated(c *gin.Context) bool {
+    return CurrentPrincipal(c) != nil
+}
+
+// Authenticate 依次尝试各认证方式，第一个识别出凭证的方式决定结果
+// 未携带任何凭证的请求作为匿名请求继续处理，是否放行由 RequireScope 决定，匿名请求的权限不高于最低的 user 角色
+func Authenticate(authenticators ...Authenticator) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        for _, authenticator := range authenticators {
+            principal, err := authenticator.Authenticate(c)
+            if errors.Is(err, ErrInvalidCredentials) {
+                abortUnauthorized(c, err)
+                return
+            }
+            if err != nil {
+                c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+                    "error": "认证失败",
+                    "details": err.Error(),
+                })
+                return
+            }
+            if principal != nil {
+                c.Set(principalContextKey, principal)
+                break
+            }
+        }
+        
+        c.Next()
+    }
+}
+
+// RequireScope 要求调用者具备 scope 权限
+// 已认证但权限不足返回 403；匿名请求在 required 为 true 或超出匿名权限时返回 401
+func RequireScope(required bool, scope string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        checkScope(c, required, scope)
+    }
+}
+
+// RequireAuthenticated required 为 true 时拒绝匿名请求，只要求认证，不校验权限范围
+func RequireAuthenticated(required bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if required && CurrentPrincipal(c) == nil {
+            abortUnauthorized(c, errors.New("需要认证"))
+            return
+        }
+        c.Next()
+    }
+}
+
+// RequireResourceScope 按请求方法校验资源权限：GET/HEAD 需要 resource:read，其余需要 resource:write
+func RequireResourceScope(required bool, resource string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        scope := resource + ":write"
+        if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+            scope = resource + ":read"
+        }
+        checkScope(c, required, scope)
+    }
+}
+
+// RequireVerifiedEmail 启用时禁止邮箱未验证的登录用户执行修改类请求，读请求和 API Key 不受影响
+func RequireVerifiedEmail(enabled bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !enabled || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+            c.Next()
+            return
+        }
+        
+        principal := CurrentPrincipal(c)
+        if principal != nil && principal.Type == model.PrincipalUser && !principal.EmailVerified {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                "error": "请先验证邮箱",
+            })
+            return
+        }
+        
+        c.Next()
+    }
+}
+
+// RequireTwoFactor 要求 roles 中角色的登录用户通过两步验证后才能访问，API Key 不受影响
+// 匿名请求只能读取，修改类请求返回 401
+func RequireTwoFactor(roles []string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        principal := CurrentPrincipal(c)
+        if principal == nil && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+            abortUnauthorized(c, errors.New("需要认证"))
+            return
+        }
+        if !twoFactorSatisfied(principal, roles) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                "error": "该操作需要启用两步验证并使用两步验证登录",
+                "code":  "two_factor_required",
+            })
+            return
+        }
+        
+        c.Next()
+    }
+}
+
+// twoFactorSatisfied 判断调用者是否满足 roles 的两步验证要求，匿名调用者没有角色，由调用方限制为只读
+func twoFactorSatisfied(principal *model.Principal, roles []string) bool {
+    if principal == nil || principal.Type != model.PrincipalUser || principal.TwoFactor {
+        return true
+    }
+    for _, role := range roles {
+        if principal.Role == role {
+            return false
+        }
+    }
+    return true
+}
+
+// RequireReadableResources 计算调用者可读取的资源，供事件流这类跨资源的接口按权限过滤
+// 规则与各资源路由相同：需要 resource:read 权限，privileged 中的资源还要求 twoFactorRoles 中的角色通过两步验证
+// 匿名请求按匿名权限过滤，required 为 true 或一种资源都不可读时返回 401；已认证但一种资源都不可读时返回 403
+func RequireReadableResources(required bool, twoFactorRoles []string, resources []string, privileged ...string) gin.HandlerFunc {
+    needsTwoFactor := make(map[string]bool, len(privileged))
+    for _, resource := range privileged {
+        needsTwoFactor[resource] = true
+    }
+    
+    return func(c *gin.Context) {
+        principal := CurrentPrincipal(c)
+        if principal == nil && required {
+            abortUnauthorized(c, errors.New("需要认证"))
+            return
+        }
+        
+        scoped := principal
+        if scoped == nil {
+            scoped = anonymous
+        }
+        
+        var readable []string
+        for _, resource := range resources {
+            if !scoped.HasScope(resource + ":read") {
+                continue
+            }
+            if needsTwoFactor[resource] && !twoFactorSatisfied(principal, twoFactorRoles) {
+                continue
+            }
+            readable = append(readable, resource)
+        }
+        if len(readable) == 0 && principal == nil {
+            abortUnauthorized(c, errors.New("需要认证"))
+            return
+        }
+        if len(readable) == 0 {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                "error": "权限不足",
+            })
+            return
+        }
+        
+        c.Set(readableResourcesKey, readable)
+        c.Next()
+    }
+}
+
+// ReadableResources 返回 RequireReadableResources 计算出的可读资源
+func ReadableResources(c *gin.Context) []string {
+    if value, ok := c.Get(readableResourcesKey); ok {
+        return value.([]string)
+    }
+    return nil
+}
+
+func checkScope(c *gin.Context, required bool, scope string) {
+    principal := CurrentPrincipal(c)
+    if principal == nil {
+        if required || !anonymous.HasScope(scope) {
+            abortUnauthorized(c, errors.New("需要认证"))
+            return
+        }
+        c.Next()
+        return
+    }
+    
+    if !principal.HasScope(scope) {
+        c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+            "error": "权限不足",
+            "scope": scope,
+        })
+        return
+    }
+    
+    c.Next()
+}
+
+func abortUnauthorized(c *gin.Context, err error) {
+    c.Writer.Header().Add("WWW-Authenticate", `Bearer realm="topService"`)
+    c.Writer.Header().Add("WWW-Authenticate", `ApiKey realm="topService"`)
+    c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+        "error": err.Error(),
+    })
+}
+
+// APIKeyAuthenticator 通过 Authorization: ApiKey <key> 或 X-API-Key 认证
+type APIKeyAuthenticator struct {
+    apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyAuthenticator(apiKeyService *service.APIKeyService) *APIKeyAuthenticator {
+    return &APIKeyAuthenticator{apiKeyService: apiKeyService}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(c *gin.Context) (*model.Principal, error) {
+    key := c.GetHeader("X-API-Key")
+    if key == "" {
+        scheme, credentials := authorizationHeader(c)
+        if !strings.EqualFold(scheme, "ApiKey") {
+            return nil, nil
+        }
+        key = credentials
+    }
+    if key == "" {
+        return nil, ErrInvalidCredentials
+    }
+    
+    apiKey, err := a.apiKeyService.Authenticate(c.Request.Context(), key)
+    if errors.Is(err, service.ErrInvalidAPIKey) {
+        return nil, ErrInvalidCredentials
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    return apiKey.Principal(), nil
+}
+
+// SessionAuthenticator 通过 Authorization: Bearer <token> 认证登录用户
+type SessionAuthenticator struct {
+    sessionService *service.SessionService
+}
+
+func NewSessionAuthenticator(sessionService *service.SessionService) *SessionAuthenticator {
+    return &SessionAuthenticator{sessionService: sessionService}
+}
+
+func (a *SessionAuthenticator) Authenticate(c *gin.Context) (*model.Principal, error) {
+    scheme, token := authorizationHeader(c)
+    if !strings.EqualFold(scheme, "Bearer") {
+        return nil, nil
+    }
+    if token == "" {
+        return nil, ErrInvalidCredentials
+    }
+    
+    user, session, err := a.sessionService.Authenticate(c.Request.Context(), token, c.ClientIP())
+    if errors.Is(err, service.ErrInvalidSession) {
+        return nil, ErrInvalidCredentials
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    principal := user.Principal()
+    principal.SessionID = session.ID
+    // 登录后关闭了两步验证的会话不再视为已通过两步验证
+    principal.TwoFactor = session.TwoFactor && user.TOTPEnabled()
+    return principal, nil
+}
+
+// authorizationHeader 拆分 Authorization 请求头为认证方案和凭证
+func authorizationHeader(c *gin.Context) (string, string) {
+    parts := strings.SplitN(strings.TrimSpace(c.GetHeader("Authorization")), " ", 2)
+    if len(parts) != 2 {
+        return parts[0], ""
+    }
+    return parts[0], strings.TrimSpace(parts[1])
+}
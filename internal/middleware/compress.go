@@ -0,0 +1,242 @@
+package middleware
+
+import (
+    "compress/gzip"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/andybalholm/brotli"
+    "github.com/gin-gonic/gin"
+)
+
+// brotliLevel 动态响应使用的 brotli 压缩级别，更高的级别压缩率提升有限但明显更慢
+const brotliLevel = 5
+
+// 支持的压缩方式，客户端权重相同时按此顺序优先
+const (
+    encodingBrotli = "br"
+    encodingGzip   = "gzip"
+)
+
+var supportedEncodings = []string{encodingBrotli, encodingGzip}
+
+// compressibleTypes 压缩的响应类型前缀，图片、xlsx 等已压缩的格式不再压缩
+var compressibleTypes = []string{
+    "application/json",
+    "application/problem+json",
+    "application/xml",
+    "application/javascript",
+    "image/svg+xml",
+    "text/",
+}
+
+// encoderPools 按压缩方式复用编码器，避免每个响应重新分配压缩窗口
+var encoderPools = map[string]*sync.Pool{
+    encodingBrotli: {New: func() interface{} {
+        return brotli.NewWriterLevel(io.Discard, brotliLevel)
+    }},
+    encodingGzip: {New: func() interface{} {
+        return gzip.NewWriter(io.Discard)
+    }},
+}
+
+// encoder 可复用的压缩编码器
+type encoder interface {
+    io.WriteCloser
+    Flush() error
+    Reset(w io.Writer)
+}
+
+// compressWriter 先缓冲响应体，达到 minSize 后再决定是否压缩；响应结束时仍不足 minSize 的原样输出
+type compressWriter struct {
+    gin.ResponseWriter
+    encoding string
+    minSize  int
+    buf      []byte
+    decided  bool
+    encoder  encoder // 为 nil 时不压缩
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+    if !w.decided {
+        w.buf = append(w.buf, data...)
+        if len(w.buf) < w.minSize {
+            return len(data), nil
+        }
+        if err := w.decide(true); err != nil {
+            return 0, err
+        }
+        return len(data), nil
+    }
+    if w.encoder != nil {
+        return w.encoder.Write(data)
+    }
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+    return w.Write([]byte(s))
+}
+
+// WriteHeaderNow 立即写出响应头时不再压缩
+func (w *compressWriter) WriteHeaderNow() {
+    if !w.decided {
+        _ = w.decide(false)
+    }
+    w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush 流式响应（如事件流）在首次刷新时决定是否压缩，之后每次刷新都把已压缩的数据发送出去
+func (w *compressWriter) Flush() {
+    if !w.decided {
+        _ = w.decide(false)
+    }
+    if w.encoder != nil {
+        _ = w.encoder.Flush()
+    }
+    w.ResponseWriter.Flush()
+}
+
+// decide 根据响应状态和类型确定是否压缩，并输出已缓冲的数据
+func (w *compressWriter) decide(compress bool) error {
+    w.decided = true
+    if compress && w.compressible() {
+        header := w.Header()
+        header.Set("Content-Encoding", w.encoding)
+        header.Del("Content-Length")
+        header.Del("Accept-Ranges")
+        // 压缩后的字节与原始响应不同，强校验 ETag 按编码加上后缀区分，如 "3" -> "3-br"
+        // 处理器比较版本号时去掉后缀，If-Match、If-None-Match 仍可使用
+        if tag := header.Get("ETag"); strings.HasPrefix(tag, "\"") {
+            header.Set("ETag", strings.TrimSuffix(tag, "\"")+"-"+w.encoding+"\"")
+        }
+        
+        w.encoder = encoderPools[w.encoding].Get().(encoder)
+        w.encoder.Reset(w.ResponseWriter)
+    }
+    
+    buf := w.buf
+    w.buf = nil
+    if len(buf) == 0 {
+        return nil
+    }
+    if w.encoder != nil {
+        _, err := w.encoder.Write(buf)
+        return err
+    }
+    _, err := w.ResponseWriter.Write(buf)
+    return err
+}
+
+// compressible 响应是否适合压缩：有完整的响应体、尚未编码且为文本类内容
+func (w *compressWriter) compressible() bool {
+    switch status := w.Status(); {
+    case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusPartialContent, status == http.StatusNotModified:
+        return false
+    }
+    
+    header := w.Header()
+    if header.Get("Content-Encoding") != "" {
+        return false
+    }
+    contentType := strings.ToLower(header.Get("Content-Type"))
+    if strings.HasPrefix(contentType, "text/event-stream") {
+        return false
+    }
+    for _, prefix := range compressibleTypes {
+        if strings.HasPrefix(contentType, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// finish 输出剩余的缓冲数据并结束压缩流
+func (w *compressWriter) finish() {
+    if !w.decided {
+        if len(w.buf) == 0 {
+            return
+        }
+        _ = w.decide(false)
+    }
+    if w.encoder != nil {
+        _ = w.encoder.Close()
+        encoderPools[w.encoding].Put(w.encoder)
+        w.encoder = nil
+    }
+}
+
+// Compress 按 Accept-Encoding 以 brotli 或 gzip 压缩响应，响应体小于 minSize 字节时不压缩
+// WebSocket 升级请求和事件流不压缩
+func Compress(minSize int) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        c.Writer.Header().Add("Vary", "Accept-Encoding")
+        
+        encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+        if encoding == "" || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+            c.Next()
+            return
+        }
+        
+        original := c.Writer
+        writer := &compressWriter{ResponseWriter: original, encoding: encoding, minSize: minSize}
+        c.Writer = writer
+        defer func() {
+            writer.finish()
+            c.Writer = original
+        }()
+        
+        c.Next()
+    }
+}
+
+// negotiateEncoding 从 Accept-Encoding 中选出权重最高的已支持压缩方式，都不接受时返回空
+func negotiateEncoding(header string) string {
+    if header == "" {
+        return ""
+    }
+    
+    weights := make(map[string]float64)
+    wildcard := -1.0
+    for _, part := range strings.Split(header, ",") {
+        name, weight := parseEncoding(part)
+        if name == "*" {
+            wildcard = weight
+            continue
+        }
+        weights[name] = weight
+    }
+    
+    best, bestWeight := "", 0.0
+    for _, encoding := range supportedEncodings {
+        weight, ok := weights[encoding]
+        if !ok {
+            weight = wildcard
+        }
+        if weight > bestWeight {
+            best, bestWeight = encoding, weight
+        }
+    }
+    return best
+}
+
+// parseEncoding 解析 "gzip;q=0.8" 形式的一项，未指定权重时为 1
+func parseEncoding(part string) (string, float64) {
+    params := strings.Split(part, ";")
+    name := strings.ToLower(strings.TrimSpace(params[0]))
+    weight := 1.0
+    for _, param := range params[1:] {
+        param = strings.TrimSpace(param)
+        if strings.HasPrefix(param, "q=") {
+            q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+            if err != nil {
+                return name, 0
+            }
+            weight = q
+        }
+    }
+    return name, weight
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "topService/internal/config"
+
+    "github.com/gin-gonic/gin"
+)
+
+// originPattern 子域名通配来源，如 https://*.example.com
+type originPattern struct {
+    prefix string // https://
+    suffix string // .example.com
+}
+
+func (p originPattern) match(origin string) bool {
+    if !strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+        return false
+    }
+    sub := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+    return sub != "" && !strings.ContainsAny(sub, "/:")
+}
+
+// corsPolicy 由配置编译得到的跨域策略
+type corsPolicy struct {
+    anyOrigin   bool
+    origins     map[string]bool
+    patterns    []originPattern
+    methods     string
+    headers     string
+    exposed     string
+    credentials bool
+    maxAge      string
+}
+
+func newCORSPolicy(cfg *config.Config) *corsPolicy {
+    policy := &corsPolicy{
+        origins:     make(map[string]bool),
+        methods:     strings.Join(cfg.CORSAllowedMethods, ", "),
+        headers:     strings.Join(cfg.CORSAllowedHeaders, ", "),
+        exposed:     strings.Join(cfg.CORSExposedHeaders, ", "),
+        credentials: cfg.CORSAllowCredentials,
+        maxAge:      strconv.Itoa(int(cfg.CORSMaxAge.Seconds())),
+    }
+    
+    for _, origin := range cfg.CORSAllowedOrigins {
+        origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+        switch {
+        case origin == "*":
+            policy.anyOrigin = true
+        case strings.Contains(origin, "://*."):
+            idx := strings.Index(origin, "*.")
+            policy.patterns = append(policy.patterns, originPattern{prefix: origin[:idx], suffix: origin[idx+1:]})
+        default:
+            policy.origins[origin] = true
+        }
+    }
+    
+    // 浏览器不接受通配来源与凭证同时出现
+    if policy.anyOrigin && policy.credentials {
+        log.Println("Warning: CORS_ALLOWED_ORIGINS contains * so credentials are disabled")
+        policy.credentials = false
+    }
+    
+    return policy
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+    origin = strings.ToLower(origin)
+    if p.anyOrigin || p.origins[origin] {
+        return true
+    }
+    for _, pattern := range p.patterns {
+        if pattern.match(origin) {
+            return true
+        }
+    }
+    return false
+}
+
+// CORS 跨域中间件
+// 仅对白名单内的来源回显 Access-Control-Allow-Origin，不在白名单内的预检请求返回 403
+func CORS(cfg *config.Config) gin.HandlerFunc {
+    policy := newCORSPolicy(cfg)
+    
+    return func(c *gin.Context) {
+        // 响应随 Origin 变化，避免缓存把一个来源的结果返回给另一个来源
+        c.Writer.Header().Add("Vary", "Origin")
+        
+        origin := c.GetHeader("Origin")
+        preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+        if origin == "" {
+            c.Next()
+            return
+        }
+        
+        if !policy.allowed(origin) {
+            if preflight {
+                c.AbortWithStatus(http.StatusForbidden)
+                return
+            }
+            c.Next()
+            return
+        }
+        
+        if policy.anyOrigin {
+            c.Header("Access-Control-Allow-Origin", "*")
+        } else {
+            c.Header("Access-Control-Allow-Origin", origin)
+        }
+        if policy.credentials {
+            c.Header("Access-Control-Allow-Credentials", "true")
+        }
+        
+        // 处理预检请求
+        if preflight {
+            c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+            c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+            c.Header("Access-Control-Allow-Methods", policy.methods)
+            c.Header("Access-Control-Allow-Headers", policy.headers)
+            c.Header("Access-Control-Max-Age", policy.maxAge)
+            c.AbortWithStatus(http.StatusNoContent)
+            return
+        }
+        
+        if policy.exposed != "" {
+            c.Header("Access-Control-Expose-Headers", policy.exposed)
+        }
+        
+        c.Next()
+    }
+}
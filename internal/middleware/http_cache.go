@@ -0,0 +1,199 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "log"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "time"
+    "topService/internal/cache"
+
+    "github.com/gin-gonic/gin"
+)
+
+// cacheControlWriter 在响应头写出前设置 Cache-Control，已由处理器或内层中间件设置的不覆盖
+type cacheControlWriter struct {
+    gin.ResponseWriter
+    value       string
+    successOnly bool // 只用于 200 和 304 响应，其他响应交给外层的默认策略
+    applied     bool
+}
+
+func (w *cacheControlWriter) apply() {
+    if w.applied {
+        return
+    }
+    w.applied = true
+    
+    header := w.Header()
+    if header.Get("Cache-Control") != "" {
+        return
+    }
+    if w.successOnly && w.Status() != http.StatusOK && w.Status() != http.StatusNotModified {
+        return
+    }
+    header.Set("Cache-Control", w.value)
+}
+
+func (w *cacheControlWriter) Write(data []byte) (int, error) {
+    w.apply()
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheControlWriter) WriteString(s string) (int, error) {
+    w.apply()
+    return w.ResponseWriter.WriteString(s)
+}
+
+func (w *cacheControlWriter) WriteHeaderNow() {
+    w.apply()
+    w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cacheControlWriter) Flush() {
+    w.apply()
+    w.ResponseWriter.Flush()
+}
+
+// cacheControl 以 value 作为响应的 Cache-Control
+func cacheControl(value string, successOnly bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        writer := &cacheControlWriter{ResponseWriter: c.Writer, value: value, successOnly: successOnly}
+        c.Writer = writer
+        c.Next()
+        // 没有响应体的响应由 gin 在最后写出响应头，这里先补上
+        writer.apply()
+    }
+}
+
+// NoStore 禁止浏览器和代理缓存响应，用于用户数据等默认场景
+func NoStore() gin.HandlerFunc {
+    return cacheControl("private, no-store", false)
+}
+
+// PublicCache 允许浏览器和共享缓存（CDN、代理）缓存成功的响应 maxAge，用于与调用者无关的公开数据
+// 错误响应不受影响，仍使用外层的默认策略；maxAge 不大于 0 时要求每次重新校验
+// authRequired 为 true、请求已认证或查询回收站数据（include_deleted=true）时响应可能因调用者而异，同样使用外层的默认策略
+func PublicCache(maxAge time.Duration, authRequired bool) gin.HandlerFunc {
+    value := "public, no-cache"
+    if maxAge > 0 {
+        value = "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+    }
+    public := cacheControl(value, true)
+    
+    return func(c *gin.Context) {
+        if authRequired || Authenticated(c) || c.Query("include_deleted") == "true" {
+            c.Next()
+            return
+        }
+        // 共享缓存不能把匿名请求的响应用于携带凭证的请求
+        c.Writer.Header().Add("Vary", "Authorization, X-API-Key")
+        public(c)
+    }
+}
+
+// cachedResponse 服务端缓存的响应
+type cachedResponse struct {
+    ContentType string `json:"content_type"`
+    Body        []byte `json:"body"`
+}
+
+// responseRecorder 在写出响应的同时保留一份响应体
+type responseRecorder struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+    w.body.Write(data)
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// ResponseCache 在服务端缓存 GET 请求的 200 响应 ttl，键由命名空间的代号、路径和规范化的查询参数组成
+// 与查询缓存使用相同的命名空间，数据变化使查询缓存失效时响应缓存一起失效（见 cache.Rotate）
+// 只能用于响应与调用者无关的路由，并应放在认证和权限检查之后；响应头 X-Cache 标明是否命中
+// store 为 nil 或 ttl 不大于 0 时不缓存；缓存读写失败时记录日志并正常处理请求
+func ResponseCache(store cache.Cache, namespace string, ttl time.Duration) gin.HandlerFunc {
+    if store == nil || ttl <= 0 {
+        return func(c *gin.Context) {
+            c.Next()
+        }
+    }
+    
+    return func(c *gin.Context) {
+        if c.Request.Method != http.MethodGet {
+            c.Next()
+            return
+        }
+        
+        ctx := c.Request.Context()
+        generation, err := cache.Generation(ctx, store, namespace)
+        if err != nil {
+            log.Printf("Failed to read cache generation of %s: %v", namespace, err)
+            c.Next()
+            return
+        }
+        key := "response:" + namespace + ":" + generation + ":" + responseCacheKey(c.Request.URL)
+        data, ok, err := store.Get(ctx, key)
+        if err != nil {
+            log.Printf("Failed to read response cache %s: %v", key, err)
+        }
+        var cached cachedResponse
+        if ok && json.Unmarshal(data, &cached) == nil {
+            c.Header("X-Cache", "HIT")
+            c.Data(http.StatusOK, cached.ContentType, cached.Body)
+            c.Abort()
+            return
+        }
+        
+        recorder := &responseRecorder{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        c.Header("X-Cache", "MISS")
+        c.Next()
+        
+        if recorder.Status() != http.StatusOK || c.IsAborted() {
+            return
+        }
+        data, err = json.Marshal(&cachedResponse{
+            ContentType: recorder.Header().Get("Content-Type"),
+            Body:        recorder.body.Bytes(),
+        })
+        if err == nil {
+            // 客户端断开后仍写入缓存，这里不使用请求的 ctx
+            err = store.Set(context.Background(), key, data, ttl)
+        }
+        if err != nil {
+            log.Printf("Failed to write response cache %s: %v", key, err)
+        }
+    }
+}
+
+// responseCacheKey 由路径和规范化的查询参数组成缓存键：参数按名称排序，同名参数的值排序，忽略空值
+// 因此 ?b=2&a=1 与 ?a=1&b=2&c= 命中同一条缓存
+func responseCacheKey(u *url.URL) string {
+    query := u.Query()
+    for name, values := range query {
+        kept := values[:0]
+        for _, value := range values {
+            if value != "" {
+                kept = append(kept, value)
+            }
+        }
+        if len(kept) == 0 {
+            delete(query, name)
+            continue
+        }
+        sort.Strings(kept)
+        query[name] = kept
+    }
+    return u.Path + "?" + query.Encode()
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "topService/internal/service"
+
+    "github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength 幂等键最大长度，与数据库列长度一致
+const maxIdempotencyKeyLength = 255
+
+// idempotencyReplayHeaders 回放时需要还原的响应头
+var idempotencyReplayHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotencyRecorder 在写出响应的同时保留一份响应体
+type idempotencyRecorder struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+    w.body.Write(data)
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 处理 Idempotency-Key 请求头
+// 同一调用者在同一路由上重复使用幂等键时回放首次响应；首次请求仍在处理时返回 409，
+// 请求体不同时返回 422。未携带该请求头的请求不受影响，5xx 响应不会被保存
+func Idempotency(idempotencyService *service.IdempotencyService) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader("Idempotency-Key")
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > maxIdempotencyKeyLength {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+                "error": "Idempotency-Key过长",
+            })
+            return
+        }
+        
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+                "error": "读取请求体失败",
+                "details": err.Error(),
+            })
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        
+        route := c.FullPath()
+        hash := sha256.New()
+        hash.Write([]byte(c.Request.Method + " " + route + "\n"))
+        hash.Write(body)
+        
+        record, err := idempotencyService.Begin(c.Request.Context(), key, route, CallerID(c), hex.EncodeToString(hash.Sum(nil)))
+        if err != nil {
+            switch {
+            case errors.Is(err, service.ErrIdempotencyInProgress):
+                c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+                    "error": err.Error(),
+                })
+            case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+                c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+                    "error": err.Error(),
+                })
+            default:
+                c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+                    "error": "处理幂等键失败",
+                    "details": err.Error(),
+                })
+            }
+            return
+        }
+        
+        // 重复请求：回放首次响应
+        if record.Completed {
+            var headers map[string]string
+            if record.Headers != "" {
+                _ = json.Unmarshal([]byte(record.Headers), &headers)
+            }
+            for name, value := range headers {
+                c.Header(name, value)
+            }
+            c.Header("Idempotent-Replayed", "true")
+            c.Status(record.StatusCode)
+            _, _ = c.Writer.WriteString(record.Body)
+            c.Abort()
+            return
+        }
+        
+        recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        
+        // 处理期间持续刷新记录，耗时较长的请求不会被重试当作已中断而重复执行
+        stopKeepAlive := idempotencyService.KeepAlive(record)
+        defer stopKeepAlive()
+        
+        // 客户端断开后仍需落库，这里不使用请求的 ctx
+        ctx := context.Background()
+        defer func() {
+            if r := recover(); r != nil {
+                if err := idempotencyService.Release(ctx, record); err != nil {
+                    log.Printf("Failed to release idempotency key: %v", err)
+                }
+                panic(r)
+            }
+        }()
+        
+        c.Next()
+        
+        status := recorder.Status()
+        if status >= http.StatusInternalServerError {
+            if err := idempotencyService.Release(ctx, record); err != nil {
+                log.Printf("Failed to release idempotency key: %v", err)
+            }
+            return
+        }
+        
+        headers := make(map[string]string, len(idempotencyReplayHeaders))
+        for _, name := range idempotencyReplayHeaders {
+            if value := recorder.Header().Get(name); value != "" {
+                headers[name] = value
+            }
+        }
+        encoded, _ := json.Marshal(headers)
+        
+        if err := idempotencyService.Complete(ctx, record, status, string(encoded), recorder.body.String()); err != nil {
+            log.Printf("Failed to save idempotent response: %v", err)
+        }
+    }
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
     "fmt"
+    "net/http"
     "time"
 
     "github.com/gin-gonic/gin"
@@ -29,20 +30,14 @@ func Recovery() gin.HandlerFunc {
     return gin.Recovery()
 }
 
-// CORS 跨域中间件
-func CORS() gin.HandlerFunc {
+// RequireIfMatch 要求修改请求携带 If-Match 头，未携带时返回 428
+// enabled 为 false 时不做任何限制，仅由处理器按需校验
+func RequireIfMatch(enabled bool) gin.HandlerFunc {
     return func(c *gin.Context) {
-        // 设置CORS头部，允许来自localhost:3000的请求
-        c.Header("Access-Control-Allow-Origin", "*")
-        c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-        c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Cache-Control, X-Requested-With, x-request-id, X-Request-ID")
-        c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-        c.Header("Access-Control-Allow-Credentials", "true")
-        c.Header("Access-Control-Max-Age", "86400")
-        
-        // 处理预检请求
-        if c.Request.Method == "OPTIONS" {
-            c.AbortWithStatus(204)
+        if enabled && c.GetHeader("If-Match") == "" {
+            c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{
+                "error": "缺少If-Match请求头",
+            })
             return
         }
         
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+    "topService/internal/config"
+
+    "github.com/gin-gonic/gin"
+)
+
+// defaultRateLimitGroup 未单独配置的路由分组使用的配额名
+const defaultRateLimitGroup = "default"
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+    Allowed    bool
+    Remaining  int           // 剩余可用请求数
+    Reset      time.Duration // 令牌桶恢复满额所需时间
+    RetryAfter time.Duration // 被拒绝时距下一个可用令牌的时间
+}
+
+// RateLimitStore 令牌桶存储
+// 单实例部署使用 MemoryRateLimitStore，多实例部署可基于 Redis 等实现该接口共享配额
+type RateLimitStore interface {
+    // Take 从 key 对应的令牌桶中取一个令牌
+    Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error)
+}
+
+// tokenBucket 令牌桶状态
+type tokenBucket struct {
+    tokens float64
+    last   time.Time
+    full   time.Time // 预计恢复满额的时间，用于清理空闲的桶
+}
+
+// MemoryRateLimitStore 进程内令牌桶存储
+type MemoryRateLimitStore struct {
+    mu        sync.Mutex
+    buckets   map[string]*tokenBucket
+    lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+    return &MemoryRateLimitStore{
+        buckets:   make(map[string]*tokenBucket),
+        lastSweep: time.Now(),
+    }
+}
+
+// Take 按 limit 补充令牌后取一个令牌
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error) {
+    now := time.Now()
+    capacity := float64(limit.Requests)
+    rate := capacity / limit.Period.Seconds()
+    
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    
+    s.sweep(now)
+    
+    bucket, ok := s.buckets[key]
+    if !ok {
+        bucket = &tokenBucket{tokens: capacity, last: now}
+        s.buckets[key] = bucket
+    }
+    
+    bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+    bucket.last = now
+    
+    result := RateLimitResult{}
+    if bucket.tokens >= 1 {
+        bucket.tokens--
+        result.Allowed = true
+    } else {
+        result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+    }
+    
+    result.Remaining = int(bucket.tokens)
+    result.Reset = secondsToDuration((capacity - bucket.tokens) / rate)
+    bucket.full = now.Add(result.Reset)
+    
+    return result, nil
+}
+
+// sweep 每分钟清理一次已恢复满额的桶，避免按 IP 建立的桶无限增长
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+    if now.Sub(s.lastSweep) < time.Minute {
+        return
+    }
+    s.lastSweep = now
+    
+    for key, bucket := range s.buckets {
+        if now.After(bucket.full) {
+            delete(s.buckets, key)
+        }
+    }
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+    return time.Duration(seconds * float64(time.Second))
+}
+
+// RateLimiter 按路由分组和调用者限流
+type RateLimiter struct {
+    store         RateLimitStore
+    enabled       bool
+    anonymous     map[string]config.RateLimit
+    authenticated map[string]config.RateLimit
+}
+
+func NewRateLimiter(cfg *config.Config, store RateLimitStore) *RateLimiter {
+    return &RateLimiter{
+        store:         store,
+        enabled:       cfg.RateLimitEnabled,
+        anonymous:     cfg.RateLimitAnonymous,
+        authenticated: cfg.RateLimitAuthenticated,
+    }
+}
+
+// Limit 返回 group 分组的限流中间件
+// 已认证请求按用户或 API Key 计数，其余按客户端 IP 计数；超出配额时返回 429
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+    return l.LimitIf(group, nil)
+}
+
+// LimitIf 与 Limit 相同，但仅对 match 返回 true 的请求计数
+func (l *RateLimiter) LimitIf(group string, match func(c *gin.Context) bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !l.enabled || (match != nil && !match(c)) {
+            c.Next()
+            return
+        }
+        
+        limits := l.anonymous
+        if Authenticated(c) {
+            limits = l.authenticated
+        }
+        limit, ok := limits[group]
+        if !ok {
+            limit, ok = limits[defaultRateLimitGroup]
+        }
+        if !ok {
+            c.Next()
+            return
+        }
+        
+        result, err := l.store.Take(c.Request.Context(), group+":"+CallerID(c), limit)
+        if err != nil {
+            // 存储不可用时放行，避免限流组件故障导致整体不可用
+            log.Printf("Rate limit store error: %v", err)
+            c.Next()
+            return
+        }
+        
+        c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds())))
+        c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+        c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+        c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+        
+        if !result.Allowed {
+            c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+            c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+                "error": "请求过于频繁，请稍后再试",
+            })
+            return
+        }
+        
+        c.Next()
+    }
+}
+
+func ceilSeconds(d time.Duration) int {
+    return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,29 @@
+package model
+
+// LoginRequest 用户名或邮箱 + 密码登录请求
+type LoginRequest struct {
+	Login    string `json:"login" binding:"required"` // 用户名或邮箱
+	Password string `json:"password" binding:"required"`
+}
+
+// EmailVerificationRequest 发送验证邮件请求
+type EmailVerificationRequest struct {
+	Locale string `json:"locale"` // zh 或 en，为空时按 Accept-Language
+}
+
+// EmailVerifyRequest 验证邮箱请求
+type EmailVerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// PasswordForgotRequest 忘记密码请求
+type PasswordForgotRequest struct {
+	Email  string `json:"email" binding:"required,email"`
+	Locale string `json:"locale"`
+}
+
+// PasswordResetRequest 重置密码请求
+type PasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt 只使用前 72 字节
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey 服务间调用使用的 API Key，只保存密钥的哈希
+type APIKey struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	Prefix     string     `json:"prefix" gorm:"not null;size:16"`        // 密钥前几位，便于识别
+	KeyHash    string     `json:"-" gorm:"not null;size:64;uniqueIndex"` // 完整密钥的 SHA-256
+	Scopes     string     `json:"-" gorm:"not null;size:500"`            // 逗号分隔的权限范围
+	CreatedBy  string     `json:"created_by" gorm:"size:100"`            // 创建者标识
+	ExpiresAt  *time.Time `json:"expires_at"`                            // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList 返回权限范围列表
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Active 判断密钥是否可用
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Principal 转换为调用者
+func (k *APIKey) Principal() *Principal {
+	return &Principal{
+		Type:          PrincipalAPIKey,
+		ID:            k.ID,
+		Name:          k.Name,
+		Scopes:        k.ScopeList(),
+		EmailVerified: true,
+	}
+}
+
+// APIKeyCreateRequest 创建 API Key 请求
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name" binding:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse API Key 响应，Key 只在创建和轮换时返回一次
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// ToResponse 转换为响应格式
+func (k *APIKey) ToResponse() *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
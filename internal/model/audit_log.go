@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 审计操作类型
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// 审计对象类型
+const (
+	AuditEntityUser    = "user"
+	AuditEntityProduct = "product"
+	AuditEntityMovie   = "movie"
+)
+
+// AuditAnonymous 未认证请求或后台任务的操作者
+const AuditAnonymous = "anonymous"
+
+// ErrAuditLogImmutable 审计日志只允许追加
+var ErrAuditLogImmutable = errors.New("审计日志不允许修改或删除")
+
+// AuditLog 一次数据修改的审计记录，只追加不修改
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	
+	Actor      string `json:"actor" gorm:"not null;size:100;index"` // 操作者标识，如 user:1、apikey:3
+	RequestID  string `json:"request_id" gorm:"size:64;index"`
+	IP         string `json:"ip" gorm:"size:64"`
+	Action     string `json:"action" gorm:"not null;size:16"`
+	EntityType string `json:"entity_type" gorm:"not null;size:32;index:idx_audit_logs_entity"`
+	EntityID   uint   `json:"entity_id" gorm:"not null;index:idx_audit_logs_entity"`
+	Changes    string `json:"-" gorm:"type:text"` // 字段级修改前后的值，JSON 格式
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// BeforeUpdate 拒绝通过 GORM 修改审计日志，数据库层另有触发器兜底
+func (AuditLog) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 拒绝通过 GORM 删除审计日志
+func (AuditLog) BeforeDelete(*gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// AuditMeta 审计日志记录的请求来源
+type AuditMeta struct {
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// FieldChange 字段修改前后的值，新建时 before 为 null，彻底删除时 after 为 null
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogFilter 审计日志查询条件，零值表示不限
+type AuditLogFilter struct {
+	EntityType string
+	EntityID   uint
+	Actor      string
+	Action     string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditLogResponse 审计日志响应
+type AuditLogResponse struct {
+	ID         uint                   `json:"id"`
+	Actor      string                 `json:"actor"`
+	RequestID  string                 `json:"request_id"`
+	IP         string                 `json:"ip"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   uint                   `json:"entity_id"`
+	Changes    map[string]FieldChange `json:"changes"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ToResponse 转换为响应格式
+func (l *AuditLog) ToResponse() *AuditLogResponse {
+	changes := map[string]FieldChange{}
+	if l.Changes != "" {
+		_ = json.Unmarshal([]byte(l.Changes), &changes)
+	}
+	
+	return &AuditLogResponse{
+		ID:         l.ID,
+		Actor:      l.Actor,
+		RequestID:  l.RequestID,
+		IP:         l.IP,
+		Action:     l.Action,
+		EntityType: l.EntityType,
+		EntityID:   l.EntityID,
+		Changes:    changes,
+		CreatedAt:  l.CreatedAt,
+	}
+}
@@ -0,0 +1,7 @@
+package model
+
+// BatchDeleteItem 批量删除条目
+type BatchDeleteItem struct {
+	ID      uint `json:"id" binding:"required"`
+	Version uint `json:"version"` // 期望的版本号，为 0 时不校验
+}
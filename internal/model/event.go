@@ -0,0 +1,118 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// 领域事件类型：<资源>.<动作>
+const (
+	EventUserCreated         = "user.created"
+	EventUserUpdated         = "user.updated"
+	EventUserDeleted         = "user.deleted"
+	EventUserRestored        = "user.restored"
+	EventUserPurged          = "user.purged"
+	EventUserDisabled        = "user.disabled"
+	EventUserEnabled         = "user.enabled"
+	EventProductCreated      = "product.created"
+	EventProductUpdated      = "product.updated"
+	EventProductDeleted      = "product.deleted"
+	EventProductRestored     = "product.restored"
+	EventProductPurged       = "product.purged"
+	EventProductStockChanged = "product.stock_changed"
+	EventMovieCreated        = "movie.created"
+	EventMovieUpdated        = "movie.updated"
+	EventMovieDeleted        = "movie.deleted"
+	EventMovieRestored       = "movie.restored"
+	EventMoviePurged         = "movie.purged"
+)
+
+// EventTypes 全部领域事件类型
+var EventTypes = []string{
+	EventUserCreated,
+	EventUserUpdated,
+	EventUserDeleted,
+	EventUserRestored,
+	EventUserPurged,
+	EventUserDisabled,
+	EventUserEnabled,
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventProductRestored,
+	EventProductPurged,
+	EventProductStockChanged,
+	EventMovieCreated,
+	EventMovieUpdated,
+	EventMovieDeleted,
+	EventMovieRestored,
+	EventMoviePurged,
+}
+
+// ValidEventPattern 判断订阅的事件类型是否合法：* 表示全部，movie.* 表示某类资源的全部事件
+func ValidEventPattern(pattern string) bool {
+	for _, eventType := range EventTypes {
+		if MatchEvent(pattern, eventType) && (pattern == eventType || strings.HasSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchEvent 判断事件类型是否匹配订阅的模式
+func MatchEvent(pattern, eventType string) bool {
+	if pattern == "*" || pattern == eventType {
+		return true
+	}
+	return strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+}
+
+// OutboxEvent 领域事件，与引发它的数据修改在同一事务中写入，提交后由分发器投递
+type OutboxEvent struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	
+	Type         string     `json:"type" gorm:"not null;size:64"`
+	ResourceType string     `json:"resource_type" gorm:"not null;size:32"` // 同审计对象类型：user、product、movie
+	ResourceID   uint       `json:"resource_id" gorm:"not null"`
+	Payload      string     `json:"-" gorm:"type:text"` // EventData 的 JSON
+	DispatchedAt *time.Time `json:"dispatched_at" gorm:"index"` // 已生成 Webhook 投递记录的时间
+}
+
+// TableName 指定表名
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// EventData 事件内容：object 为修改后的资源（彻底删除时为删除前），changes 为有变化的字段
+type EventData struct {
+	Object  interface{}            `json:"object"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// EventMessage 推送给订阅方的事件格式
+type EventMessage struct {
+	ID           uint            `json:"id"`
+	Type         string          `json:"type"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   uint            `json:"resource_id"`
+	Data         json.RawMessage `json:"data"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Message 转换为推送格式
+func (e *OutboxEvent) Message() *EventMessage {
+	data := json.RawMessage(e.Payload)
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+	return &EventMessage{
+		ID:           e.ID,
+		Type:         e.Type,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Data:         data,
+		CreatedAt:    e.CreatedAt,
+	}
+}
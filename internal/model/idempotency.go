@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// IdempotencyRecord 幂等键记录，保存首次请求的响应用于重试时回放
+type IdempotencyRecord struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	Key         string    `json:"key" gorm:"column:idempotency_key;not null;size:255;uniqueIndex:idx_idempotency_key_route_caller,priority:1"`
+	Route       string    `json:"route" gorm:"not null;size:255;uniqueIndex:idx_idempotency_key_route_caller,priority:2"`
+	Caller      string    `json:"caller" gorm:"not null;size:100;uniqueIndex:idx_idempotency_key_route_caller,priority:3"`
+	RequestHash string    `json:"request_hash" gorm:"not null;size:64"`
+	Completed   bool      `json:"completed" gorm:"not null;default:false"` // false 表示首次请求仍在处理中
+	StatusCode  int       `json:"status_code"`
+	Headers     string    `json:"headers" gorm:"type:text"` // 需要回放的响应头（JSON）
+	Body        string    `json:"body" gorm:"type:mediumtext"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+// TableName 指定表名
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_records"
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 任务状态
+const (
+	JobStatusPending   = "pending"   // 等待执行、等待重试或计划在 run_at 执行
+	JobStatusRunning   = "running"   // 正在由某个 worker 执行
+	JobStatusSucceeded = "succeeded" // 执行成功
+	JobStatusFailed    = "failed"    // 重试次数用完或不可重试的错误
+	JobStatusCanceled  = "canceled"  // 执行前被取消
+)
+
+// 任务类型
+const (
+	JobMovieImport = "movie.import"      // 电影导入
+	JobExport      = "export"            // 导出用户、产品或电影到文件
+	JobSendEmail   = "email.send"        // 发送验证邮件或密码重置邮件
+	JobCheckVideo  = "movie.check_video" // 检查电影播放地址（M3u8）是否可用
+)
+
+// Job 持久化在数据库中的后台任务
+type Job struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	Type        string     `json:"type" gorm:"not null;size:64;index"`
+	Payload     string     `json:"-" gorm:"type:longtext"` // 任务参数的 JSON
+	Status      string     `json:"status" gorm:"not null;size:16;index:idx_jobs_due"`
+	RunAt       time.Time  `json:"run_at" gorm:"index:idx_jobs_due"` // 最早执行时间，重试时为下次执行时间
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null;default:1"`
+	Processed   int        `json:"processed" gorm:"not null;default:0"`
+	Total       int        `json:"total" gorm:"not null;default:0"` // 0 表示总量未知
+	Result      string     `json:"-" gorm:"type:longtext"`          // 执行结果的 JSON，执行中为阶段性结果
+	Error       string     `json:"error" gorm:"size:1000"`
+	Actor       string     `json:"actor" gorm:"size:100;index"` // 创建任务的调用者，同审计日志
+	RequestID   string     `json:"request_id" gorm:"size:64"`
+	IP          string     `json:"-" gorm:"size:64"`
+	LockedBy    string     `json:"-" gorm:"size:100"` // 正在执行的 worker
+	LockedUntil *time.Time `json:"-"`                 // 租约到期时间，worker 失联后由其他 worker 接管
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCanceled
+}
+
+// JobFilter 任务查询条件
+type JobFilter struct {
+	Type   string
+	Status string
+	Actor  string // 非空时只返回该调用者创建的任务
+	
+	// ExcludeTypes 不返回的任务类型，如普通用户不可见的邮件任务
+	ExcludeTypes []string
+}
+
+// JobResponse 任务响应
+type JobResponse struct {
+	ID          uint            `json:"id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Processed   int             `json:"processed"`
+	Total       int             `json:"total"`
+	Progress    *float64        `json:"progress"` // 0-100，总量未知时为 null
+	Result      json.RawMessage `json:"result"`
+	Error       string          `json:"error,omitempty"`
+	Actor       string          `json:"actor"`
+	RequestID   string          `json:"request_id,omitempty"`
+	DownloadURL string          `json:"download_url,omitempty"`
+	RunAt       time.Time       `json:"run_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// ToResponse 转换为响应格式
+func (j *Job) ToResponse() *JobResponse {
+	response := &JobResponse{
+		ID:          j.ID,
+		Type:        j.Type,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		Processed:   j.Processed,
+		Total:       j.Total,
+		Result:      json.RawMessage("null"),
+		Error:       j.Error,
+		Actor:       j.Actor,
+		RequestID:   j.RequestID,
+		RunAt:       j.RunAt,
+		CreatedAt:   j.CreatedAt,
+		StartedAt:   j.StartedAt,
+		FinishedAt:  j.FinishedAt,
+	}
+	if j.Result != "" {
+		response.Result = json.RawMessage(j.Result)
+	}
+	
+	switch {
+	case j.Status == JobStatusSucceeded:
+		progress := 100.0
+		response.Progress = &progress
+	case j.Total > 0:
+		progress := float64(j.Processed) * 100 / float64(j.Total)
+		response.Progress = &progress
+	}
+	return response
+}
+
+// ExportRequest export 任务参数，筛选条件与对应的列表接口相同
+type ExportRequest struct {
+	Resource       string `json:"resource"` // users / products / movies
+	Format         string `json:"format"`
+	Fields         string `json:"fields,omitempty"`
+	Keyword        string `json:"keyword,omitempty"`
+	Category       string `json:"category,omitempty"`
+	Genre          string `json:"genre,omitempty"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+}
+
+// ExportResult export 任务结果，文件通过 GET /api/v1/jobs/:id/download 下载
+type ExportResult struct {
+	Filename    string `json:"filename"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Rows        int    `json:"rows"`
+}
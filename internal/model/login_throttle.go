@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LoginThrottle 登录失败计数，Subject 为 user:<id>、login:<用户名或邮箱> 或 ip:<地址>
+type LoginThrottle struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	Subject       string     `json:"subject" gorm:"not null;size:191;uniqueIndex"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"index"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// TableName 指定表名
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
@@ -2,13 +2,17 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Movie struct {
-	ID          uint      `json:"id" gorm:"primarykey;comment:电影ID"`
-	CreatedAt   time.Time `json:"created_at" gorm:"column:create_at;comment:创建时间"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"column:update_at;comment:更新时间"`
-	
+	ID        uint           `json:"id" gorm:"primarykey;comment:电影ID"`
+	CreatedAt time.Time      `json:"created_at" gorm:"column:create_at;comment:创建时间"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"column:update_at;comment:更新时间"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:delete_at;index;comment:删除时间"`
+	Version   uint           `json:"version" gorm:"not null;default:1;comment:版本号（乐观锁）"`
+
 	Title       string     `json:"title" gorm:"not null;size:255;comment:电影名称" binding:"required,min=1,max=255"`
 	Cover       string     `json:"cover" gorm:"size:255;comment:封面"`
 	Genre       string     `json:"genre" gorm:"size:100;comment:电影类型"`
@@ -44,22 +48,29 @@ type MovieCreateRequest struct {
 	Description string     `json:"description"`
 }
 
-// MovieUpdateRequest 更新电影请求
+// MovieUpdateRequest 更新电影请求（PUT 全量替换，也是 PATCH 的基准文档）
 type MovieUpdateRequest struct {
-	Title       string     `json:"title" binding:"omitempty,min=1,max=255"`
+	Title       string     `json:"title" binding:"required,min=1,max=255"`
 	Cover       string     `json:"cover"`
 	Genre       string     `json:"genre"`
 	Director    string     `json:"director"`
 	M3u8        string     `json:"m3u8"`
 	Actors      string     `json:"actors"`
 	ReleaseDate *time.Time `json:"release_date"`
-	Duration    *int       `json:"duration" binding:"omitempty,min=0"`
+	Duration    int        `json:"duration" binding:"min=0"`
 	Language    string     `json:"language"`
 	Country     string     `json:"country"`
-	Rating      *float32   `json:"rating" binding:"omitempty,min=0,max=10"`
+	Rating      float32    `json:"rating" binding:"min=0,max=10"`
 	Description string     `json:"description"`
 }
 
+// MovieBatchUpdateItem 批量更新电影条目，字段与全量更新请求一致
+type MovieBatchUpdateItem struct {
+	ID      uint `json:"id" binding:"required"`
+	Version uint `json:"version"` // 期望的版本号，为 0 时不校验
+	MovieUpdateRequest
+}
+
 // MovieResponse 电影响应
 type MovieResponse struct {
     ID          uint       `json:"id"`
@@ -75,8 +86,10 @@ type MovieResponse struct {
     Country     string     `json:"country"`
     Rating      float32    `json:"rating"`
     Description string     `json:"description"`
+    Version     uint       `json:"version"`
     CreatedAt   time.Time  `json:"created_at"`
     UpdatedAt   time.Time  `json:"updated_at"`
+    DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 
@@ -96,7 +109,27 @@ func (m *Movie) ToResponse() *MovieResponse {
         Country:     m.Country,
         Rating:      m.Rating,
         Description: m.Description,
+        Version:     m.Version,
         CreatedAt:   m.CreatedAt,
         UpdatedAt:   m.UpdatedAt,
+        DeletedAt:   deletedTime(m.DeletedAt),
+    }
+}
+
+// ToUpdateRequest 转换为全量更新请求
+func (m *Movie) ToUpdateRequest() *MovieUpdateRequest {
+    return &MovieUpdateRequest{
+        Title:       m.Title,
+        Cover:       m.Cover,
+        Genre:       m.Genre,
+        Director:    m.Director,
+        M3u8:        m.M3u8,
+        Actors:      m.Actors,
+        ReleaseDate: m.ReleaseDate,
+        Duration:    m.Duration,
+        Language:    m.Language,
+        Country:     m.Country,
+        Rating:      m.Rating,
+        Description: m.Description,
     }
 }
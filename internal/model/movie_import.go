@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// ImportRowError 导入文件中某一行的错误
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+// ImportDuplicate 导入文件中被去重跳过的行
+type ImportDuplicate struct {
+	Line        int    `json:"line"`
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	Reason      string `json:"reason"` // file: 文件内重复 existing: 与已有电影重复
+}
+
+// MovieImportReport 电影导入校验报告
+type MovieImportReport struct {
+	Total      int               `json:"total"`
+	Valid      int               `json:"valid"`
+	Invalid    int               `json:"invalid"`
+	Duplicates []ImportDuplicate `json:"duplicates"`
+	Errors     []ImportRowError  `json:"errors"`
+}
+
+// MovieImportResult 导入任务结果，执行中为阶段性结果
+type MovieImportResult struct {
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// MovieImportJob 电影导入任务，由 movie.import 类型的后台任务转换而来
+type MovieImportJob struct {
+	ID         uint             `json:"id"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Processed  int              `json:"processed"`
+	Created    int              `json:"created"`
+	Failed     int              `json:"failed"`
+	Errors     []ImportRowError `json:"errors"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// MovieImportRow 通过校验、等待导入的行
+type MovieImportRow struct {
+	Line    int                 `json:"line"`
+	Request *MovieCreateRequest `json:"request"`
+}
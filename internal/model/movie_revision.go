@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MovieRevision 电影某个版本的内容快照，更新电影时记录，用于查看历史和回滚
+type MovieRevision struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	
+	MovieID   uint   `json:"movie_id" gorm:"not null;uniqueIndex:idx_movie_revisions_version"`
+	Version   uint   `json:"version" gorm:"not null;uniqueIndex:idx_movie_revisions_version"`
+	Actor     string `json:"actor" gorm:"size:100"` // 产生该版本的操作者，补记的历史版本为空
+	RequestID string `json:"request_id" gorm:"size:64"`
+	Snapshot  string `json:"-" gorm:"type:text"` // 版本内容，MovieUpdateRequest 的 JSON
+}
+
+// TableName 指定表名
+func (MovieRevision) TableName() string {
+	return "movie_revisions"
+}
+
+// Content 解析版本内容
+func (r *MovieRevision) Content() (*MovieUpdateRequest, error) {
+	var content MovieUpdateRequest
+	if err := json.Unmarshal([]byte(r.Snapshot), &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// MovieRevisionResponse 电影版本响应，movie 与全量更新请求格式相同
+type MovieRevisionResponse struct {
+	ID        uint                `json:"id"`
+	MovieID   uint                `json:"movie_id"`
+	Version   uint                `json:"version"`
+	Actor     string              `json:"actor,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	Movie     *MovieUpdateRequest `json:"movie"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// ToResponse 转换为响应格式
+func (r *MovieRevision) ToResponse() *MovieRevisionResponse {
+	content, _ := r.Content()
+	return &MovieRevisionResponse{
+		ID:        r.ID,
+		MovieID:   r.MovieID,
+		Version:   r.Version,
+		Actor:     r.Actor,
+		RequestID: r.RequestID,
+		Movie:     content,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+// MovieRevisionDiff 两个版本之间有变化的字段
+type MovieRevisionDiff struct {
+	MovieID uint                   `json:"movie_id"`
+	From    uint                   `json:"from"`
+	To      uint                   `json:"to"`
+	Changes map[string]FieldChange `json:"changes"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GenreStat 某一类型的电影数量
+type GenreStat struct {
+	Genre string `json:"genre"`
+	Count int64  `json:"count"`
+}
+
+// MovieStats 电影统计快照，由定时任务 movie.stats 重新计算，表中只有一行
+type MovieStats struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement:false"`
+	Total      int64
+	AvgRating  float64
+	GenreStats string    `gorm:"type:text"` // []GenreStat 的 JSON
+	ComputedAt time.Time
+}
+
+// TableName 指定表名
+func (MovieStats) TableName() string {
+	return "movie_stats"
+}
+
+// MovieStatsResponse 电影统计响应
+type MovieStatsResponse struct {
+	Total      int64       `json:"total"`
+	AvgRating  float64     `json:"avg_rating"`
+	GenreStats []GenreStat `json:"genre_stats"`
+	ComputedAt time.Time   `json:"computed_at"` // 统计的计算时间，定时任务每次重新计算后更新
+}
+
+// ToResponse 转换为响应格式
+func (s *MovieStats) ToResponse() *MovieStatsResponse {
+	response := &MovieStatsResponse{
+		Total:      s.Total,
+		AvgRating:  s.AvgRating,
+		ComputedAt: s.ComputedAt,
+	}
+	if s.GenreStats != "" {
+		_ = json.Unmarshal([]byte(s.GenreStats), &response.GenreStats)
+	}
+	if response.GenreStats == nil {
+		response.GenreStats = []GenreStat{}
+	}
+	return response
+}
@@ -0,0 +1,78 @@
+package model
+
+import "strconv"
+
+// 调用者类型
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "apikey"
+)
+
+// 权限范围，* 表示全部权限
+const (
+	ScopeAll           = "*"
+	ScopeAdmin         = "admin"
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeMoviesRead    = "movies:read"
+	ScopeMoviesWrite   = "movies:write"
+)
+
+// Scopes 可授予的全部权限范围
+var Scopes = []string{
+	ScopeAll,
+	ScopeAdmin,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeProductsRead,
+	ScopeProductsWrite,
+	ScopeMoviesRead,
+	ScopeMoviesWrite,
+}
+
+// ValidScope 判断权限范围是否存在
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal 已认证的调用者，可以是用户或 API Key
+type Principal struct {
+	Type   string   `json:"type"`
+	ID     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	
+	// Role 用户角色，API Key 为空
+	Role string `json:"role,omitempty"`
+	
+	// EmailVerified 用户是否已验证邮箱，API Key 始终为 true
+	EmailVerified bool `json:"email_verified"`
+	
+	// SessionID 登录用户当前会话的 ID，API Key 为 0
+	SessionID uint `json:"session_id,omitempty"`
+	
+	// TwoFactor 本次会话是否通过了两步验证
+	TwoFactor bool `json:"two_factor"`
+}
+
+// HasScope 判断调用者是否具备指定权限
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == ScopeAll || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// String 返回调用者标识，如 user:1、apikey:3
+func (p *Principal) String() string {
+	return p.Type + ":" + strconv.FormatUint(uint64(p.ID), 10)
+}
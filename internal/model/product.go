@@ -11,6 +11,7 @@ type Product struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	Version     uint           `json:"version" gorm:"not null;default:1"` // 乐观锁版本号
 	
 	Name        string  `json:"name" gorm:"not null;size:100" binding:"required,min=1,max=100"`
 	Description string  `json:"description" gorm:"size:500"`
@@ -34,14 +35,21 @@ type ProductCreateRequest struct {
 	Category    string  `json:"category"`
 }
 
-// ProductUpdateRequest 更新产品请求
+// ProductUpdateRequest 更新产品请求（PUT 全量替换，也是 PATCH 的基准文档）
 type ProductUpdateRequest struct {
-	Name        string   `json:"name" binding:"omitempty,min=1,max=100"`
-	Description string   `json:"description"`
-	Price       *float64 `json:"price" binding:"omitempty,gt=0"`
-	Stock       *int     `json:"stock" binding:"omitempty,min=0"`
-	Category    string   `json:"category"`
-	Status      *int     `json:"status" binding:"omitempty,oneof=0 1"`
+	Name        string  `json:"name" binding:"required,min=1,max=100"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price" binding:"required,gt=0"`
+	Stock       int     `json:"stock" binding:"min=0"`
+	Category    string  `json:"category"`
+	Status      *int    `json:"status" binding:"required,oneof=0 1"`
+}
+
+// ProductBatchUpdateItem 批量更新产品条目，字段与全量更新请求一致
+type ProductBatchUpdateItem struct {
+	ID      uint `json:"id" binding:"required"`
+	Version uint `json:"version"` // 期望的版本号，为 0 时不校验
+	ProductUpdateRequest
 }
 
 // ToResponse 转换为响应格式
@@ -54,20 +62,37 @@ func (p *Product) ToResponse() *ProductResponse {
 		Stock:       p.Stock,
 		Category:    p.Category,
 		Status:      p.Status,
+		Version:     p.Version,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+		DeletedAt:   deletedTime(p.DeletedAt),
+	}
+}
+
+// ToUpdateRequest 转换为全量更新请求
+func (p *Product) ToUpdateRequest() *ProductUpdateRequest {
+	status := p.Status
+	return &ProductUpdateRequest{
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Stock:       p.Stock,
+		Category:    p.Category,
+		Status:      &status,
 	}
 }
 
 // ProductResponse 产品响应
 type ProductResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
-	Category    string    `json:"category"`
-	Status      int       `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       float64    `json:"price"`
+	Stock       int        `json:"stock"`
+	Category    string     `json:"category"`
+	Status      int        `json:"status"`
+	Version     uint       `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RecoveryCode 两步验证恢复码，丢失验证器时代替动态码使用一次；只保存 SHA-256 哈希
+type RecoveryCode struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	
+	UserID   uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash string     `json:"-" gorm:"not null;size:64"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// TOTPEnrollResponse 开始启用两步验证的响应
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // 可渲染为二维码供验证器应用扫描
+}
+
+// TOTPCodeRequest 提交动态码或恢复码的请求
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest 登录第二步请求
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"` // 第一步登录返回的令牌
+	Code           string `json:"code" binding:"required"`            // 动态码或恢复码
+}
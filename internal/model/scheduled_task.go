@@ -0,0 +1,91 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 定时任务
+const (
+	TaskMovieStats   = "movie.stats"       // 重新计算电影统计
+	TaskTrashPurge   = "trash.purge"       // 彻底删除超过保留期的软删除数据
+	TaskExpireTokens = "tokens.expire"     // 清理过期的会话和一次性令牌
+	TaskVideoCheck   = "movie.check_video" // 创建播放地址检查任务
+)
+
+// 定时任务的触发方式
+const (
+	TaskTriggerSchedule = "schedule" // 按 cron 表达式到期触发
+	TaskTriggerManual   = "manual"   // 管理员手动触发
+)
+
+// 定时任务执行状态
+const (
+	TaskRunRunning   = "running"
+	TaskRunSucceeded = "succeeded"
+	TaskRunFailed    = "failed"
+)
+
+// ScheduledTask 定时任务的调度状态，由所有实例共享
+// 实例以条件更新抢占租约，同一任务同一时间只由持有租约的实例执行
+type ScheduledTask struct {
+	Name        string     `gorm:"primaryKey;size:64"`
+	UpdatedAt   time.Time
+	Schedule    string     `gorm:"size:100"` // 计算 NextRunAt 时使用的 cron 表达式，配置变化后重新计算
+	NextRunAt   *time.Time // 下次执行时间，未配置 cron 表达式时为空
+	LockedBy    string     `gorm:"size:100"` // 正在执行的实例
+	LockedUntil *time.Time // 租约到期时间，实例失联后由其他实例接管
+}
+
+// TableName 指定表名
+func (ScheduledTask) TableName() string {
+	return "scheduled_tasks"
+}
+
+// TaskRun 定时任务的一次执行记录
+type TaskRun struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Task       string     `json:"task" gorm:"not null;size:64;index"`
+	Trigger    string     `json:"trigger" gorm:"not null;size:16"`
+	Actor      string     `json:"actor" gorm:"size:100"` // 手动触发的调用者
+	Instance   string     `json:"instance" gorm:"size:100"`
+	Status     string     `json:"status" gorm:"not null;size:16"`
+	Result     string     `json:"-" gorm:"type:text"` // 执行结果的 JSON
+	Error      string     `json:"error,omitempty" gorm:"size:1000"`
+	StartedAt  time.Time  `json:"started_at" gorm:"index"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// TableName 指定表名
+func (TaskRun) TableName() string {
+	return "task_runs"
+}
+
+// TaskRunResponse 执行记录响应
+type TaskRunResponse struct {
+	*TaskRun
+	Result     json.RawMessage `json:"result"`
+	DurationMS *int64          `json:"duration_ms"`
+}
+
+// ToResponse 转换为响应格式
+func (r *TaskRun) ToResponse() *TaskRunResponse {
+	response := &TaskRunResponse{TaskRun: r, Result: json.RawMessage("null")}
+	if r.Result != "" {
+		response.Result = json.RawMessage(r.Result)
+	}
+	if r.FinishedAt != nil {
+		duration := r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+		response.DurationMS = &duration
+	}
+	return response
+}
+
+// ScheduledTaskResponse 定时任务响应
+type ScheduledTaskResponse struct {
+	Name      string           `json:"name"`
+	Schedule  string           `json:"schedule"` // 为空表示只能手动触发
+	NextRunAt *time.Time       `json:"next_run_at"`
+	Running   bool             `json:"running"`
+	LastRun   *TaskRunResponse `json:"last_run"`
+}
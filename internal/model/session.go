@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// Session 用户登录会话，对应一个会话令牌（jti）；吊销或过期后令牌失效
+type Session struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	
+	TokenID    string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	UserAgent  string     `json:"user_agent" gorm:"size:255"`
+	IP         string     `json:"ip" gorm:"size:64"` // 最近一次使用的客户端 IP
+	TwoFactor  bool       `json:"two_factor" gorm:"not null;default:false"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// TableName 指定表名
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active 会话是否仍然有效
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// SessionMeta 登录时记录的客户端信息
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionResponse 会话响应，current 表示是否为发起请求的会话
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	TwoFactor  bool      `json:"two_factor"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ToResponse 转换为响应格式
+func (s *Session) ToResponse(currentID uint) *SessionResponse {
+	return &SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		TwoFactor:  s.TwoFactor,
+		Current:    s.ID == currentID,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
@@ -11,11 +11,25 @@ type User struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	Version   uint           `json:"version" gorm:"not null;default:1"` // 乐观锁版本号
 	
-	Username string `json:"username" gorm:"uniqueIndex;not null;size:50" binding:"required,min=3,max=50"`
-	Email    string `json:"email" gorm:"uniqueIndex;not null;size:100" binding:"required,email"`
+	Username string `json:"username" gorm:"uniqueIndex:idx_users_username_active;not null;size:50" binding:"required,min=3,max=50"`
+	Email    string `json:"email" gorm:"uniqueIndex:idx_users_email_active;not null;size:100" binding:"required,email"`
 	Phone    string `json:"phone" gorm:"size:20"`
-	Status   int    `json:"status" gorm:"default:1"` // 1:活跃 0:禁用
+	Status   int    `json:"status" gorm:"default:1"`                   // 1:活跃 0:禁用
+	Role     string `json:"role" gorm:"not null;size:20;default:user"` // user / editor / admin
+	
+	PasswordHash    string     `json:"-" gorm:"size:100"` // bcrypt 哈希，仅通过 OIDC 登录的用户为空
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	PendingEmail    string     `json:"-" gorm:"size:100"` // 等待确认的新邮箱，确认前登录和邮件仍使用原邮箱
+	
+	// 两步验证（TOTP），TOTPSecret 已生成但 TOTPEnabledAt 为空表示尚未确认
+	TOTPSecret    string     `json:"-" gorm:"size:64"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"` // 最近一次使用的时间步，防止动态码重放
+	
+	// DeletedID 未删除时为0，软删除后记为自身ID，使用户名/邮箱唯一索引忽略已删除用户
+	DeletedID uint `json:"-" gorm:"not null;default:0;uniqueIndex:idx_users_username_active;uniqueIndex:idx_users_email_active"`
 }
 
 // TableName 指定表名
@@ -23,6 +37,43 @@ func (User) TableName() string {
 	return "users"
 }
 
+// 用户角色
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// roleScopes 各角色拥有的权限范围
+var roleScopes = map[string][]string{
+	RoleUser:   {ScopeProductsRead, ScopeMoviesRead},
+	RoleEditor: {ScopeProductsRead, ScopeProductsWrite, ScopeMoviesRead, ScopeMoviesWrite},
+	RoleAdmin:  {ScopeAll},
+}
+
+// AnonymousScopes 不要求认证时匿名请求拥有的权限范围，与权限最低的 user 角色相同，只能读取
+func AnonymousScopes() []string {
+	return roleScopes[RoleUser]
+}
+
+// ValidRole 判断角色是否存在
+func ValidRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// Principal 转换为调用者，权限由角色决定
+func (u *User) Principal() *Principal {
+	return &Principal{
+		Type:          PrincipalUser,
+		ID:            u.ID,
+		Name:          u.Username,
+		Scopes:        roleScopes[u.Role],
+		Role:          u.Role,
+		EmailVerified: u.EmailVerifiedAt != nil,
+	}
+}
+
 // UserCreateRequest 创建用户请求
 type UserCreateRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
@@ -30,34 +81,69 @@ type UserCreateRequest struct {
 	Phone    string `json:"phone"`
 }
 
-// UserUpdateRequest 更新用户请求
+// UserUpdateRequest 更新用户请求（PUT 全量替换，也是 PATCH 的基准文档）
 type UserUpdateRequest struct {
-	Username string `json:"username" binding:"omitempty,min=3,max=50"`
-	Email    string `json:"email" binding:"omitempty,email"`
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email"`
 	Phone    string `json:"phone"`
-	Status   *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	Status   *int   `json:"status" binding:"required,oneof=0 1"`
+}
+
+// UserBatchUpdateItem 批量更新用户条目，字段与全量更新请求一致
+type UserBatchUpdateItem struct {
+	ID      uint `json:"id" binding:"required"`
+	Version uint `json:"version"` // 期望的版本号，为 0 时不校验
+	UserUpdateRequest
+}
+
+// TOTPEnabled 是否已启用两步验证
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
 }
 
 // UserResponse 用户响应
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
-	Status    int       `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	Status          int        `json:"status"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	PendingEmail    string     `json:"pending_email,omitempty"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	Version         uint       `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ToResponse 转换为响应格式
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		Phone:     u.Phone,
-		Status:    u.Status,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		Phone:           u.Phone,
+		Status:          u.Status,
+		Role:            u.Role,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		PendingEmail:    u.PendingEmail,
+		TOTPEnabled:     u.TOTPEnabled(),
+		Version:         u.Version,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+		DeletedAt:       deletedTime(u.DeletedAt),
+	}
+}
+
+// ToUpdateRequest 转换为全量更新请求
+func (u *User) ToUpdateRequest() *UserUpdateRequest {
+	status := u.Status
+	return &UserUpdateRequest{
+		Username: u.Username,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Status:   &status,
 	}
-}
\ No newline at end of file
+}
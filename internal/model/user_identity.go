@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// UserIdentity 用户在外部身份提供方（OIDC）的账号，按 issuer + subject 唯一
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	UserID  uint   `json:"user_id" gorm:"not null;index"`
+	Issuer  string `json:"issuer" gorm:"not null;size:255;uniqueIndex:idx_user_identities_issuer_subject,priority:1"`
+	Subject string `json:"subject" gorm:"not null;size:255;uniqueIndex:idx_user_identities_issuer_subject,priority:2"`
+	Email   string `json:"email" gorm:"size:100"` // 最近一次登录时 IdP 提供的邮箱
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// 一次性令牌用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	TokenPurposeChangeEmail   = "change_email" // 确认修改邮箱，令牌只发送到新邮箱
+	TokenPurposeTwoFactor     = "two_factor"   // 密码或 OIDC 登录通过后等待提交动态码
+)
+
+// UserToken 邮件链接、登录第二步等使用的一次性令牌，令牌本身为签名的 JWT，这里记录其 jti 保证只能使用一次
+type UserToken struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	
+	TokenID   string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null;size:30"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	Attempts  int        `json:"-" gorm:"not null;default:0"` // 提交错误的次数，用于限制登录第二步的尝试次数
+}
+
+// TableName 指定表名
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// 播放地址检查结果
+const (
+	VideoStatusOK     = "ok"     // 返回 2xx 且内容是 M3u8 播放列表
+	VideoStatusBroken = "broken" // 无法访问、返回错误状态码或内容不是播放列表
+)
+
+// MovieVideoCheck 电影播放地址最近一次的检查结果，每部电影一条
+type MovieVideoCheck struct {
+	MovieID    uint      `json:"movie_id" gorm:"primaryKey;autoIncrement:false"`
+	URL        string    `json:"url" gorm:"size:500"`
+	Status     string    `json:"status" gorm:"not null;size:16;index"`
+	HTTPStatus int       `json:"http_status"`
+	Error      string    `json:"error" gorm:"size:500"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// TableName 指定表名
+func (MovieVideoCheck) TableName() string {
+	return "movie_video_checks"
+}
+
+// VideoCheckRequest 发起播放地址检查的请求，movie_ids 为空时检查全部有播放地址的电影
+type VideoCheckRequest struct {
+	MovieIDs []uint `json:"movie_ids" binding:"max=1000"`
+}
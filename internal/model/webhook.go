@@ -0,0 +1,116 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook 投递状态
+const (
+	DeliveryPending   = "pending"   // 等待投递或等待重试
+	DeliverySucceeded = "succeeded" // 对方返回 2xx
+	DeliveryDead      = "dead"      // 重试次数用完，进入死信，可手动重新投递
+)
+
+// Webhook 订阅领域事件的外部地址
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	URL         string `json:"url" gorm:"not null;size:500"`
+	Description string `json:"description" gorm:"size:255"`
+	Events      string `json:"-" gorm:"not null;size:1000"` // 逗号分隔的事件类型或模式
+	Secret      string `json:"-" gorm:"not null;size:100"`  // HMAC 签名密钥
+	Active      bool   `json:"active" gorm:"not null;default:true"`
+	CreatedBy   string `json:"created_by" gorm:"size:100"`
+}
+
+// TableName 指定表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// EventList 返回订阅的事件类型列表
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes 判断是否订阅了事件类型
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, pattern := range w.EventList() {
+		if MatchEvent(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookCreateRequest 创建 Webhook 请求
+type WebhookCreateRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1,dive,required"`
+}
+
+// WebhookUpdateRequest 更新 Webhook 请求，未提供的字段保持不变
+type WebhookUpdateRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=500"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Events      []string `json:"events" binding:"omitempty,min=1,dive,required"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookResponse Webhook 响应，Secret 只在创建和轮换时返回
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedBy   string    `json:"created_by"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToResponse 转换为响应格式
+func (w *Webhook) ToResponse() *WebhookResponse {
+	return &WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		Description: w.Description,
+		Events:      w.EventList(),
+		Active:      w.Active,
+		CreatedBy:   w.CreatedBy,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+// WebhookDelivery 一个事件向一个 Webhook 的投递记录
+type WebhookDelivery struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	EventID        uint       `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null;size:64"`
+	Status         string     `json:"status" gorm:"not null;size:16;index:idx_webhook_deliveries_due"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body" gorm:"size:1000"` // 截断后的响应内容
+	Error          string     `json:"error" gorm:"size:500"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
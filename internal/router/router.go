@@ -2,12 +2,17 @@ package router
 
 import (
 	"net/http"
+	"topService/internal/cache"
+	"topService/internal/config"
 	"topService/internal/handler"
+	"topService/internal/middleware"
+	"topService/internal/model"
+	"topService/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, userHandler *handler.UserHandler, productHandler *handler.ProductHandler, movieHandler *handler.MovieHandler) {
+func SetupRoutes(r *gin.Engine, cfg *config.Config, userHandler *handler.UserHandler, productHandler *handler.ProductHandler, movieHandler *handler.MovieHandler, movieImportHandler *handler.MovieImportHandler, trashHandler *handler.TrashHandler, idempotencyService *service.IdempotencyService, rateLimiter *middleware.RateLimiter, apiKeyHandler *handler.APIKeyHandler, authHandler *handler.AuthHandler, auditHandler *handler.AuditHandler, webhookHandler *handler.WebhookHandler, eventHandler *handler.EventHandler, jobHandler *handler.JobHandler, videoCheckHandler *handler.VideoCheckHandler, taskHandler *handler.TaskHandler, responseCache cache.Cache, authenticators []middleware.Authenticator) {
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -16,40 +21,172 @@ func SetupRoutes(r *gin.Engine, userHandler *handler.UserHandler, productHandler
 		})
 	})
 	
+	// 修改类请求的 If-Match 校验
+	ifMatch := middleware.RequireIfMatch(cfg.RequireIfMatch)
+	
+	// 创建类请求支持 Idempotency-Key 重试
+	idempotent := middleware.Idempotency(idempotencyService)
+	
+	// 带搜索关键字的电影列表走 LIKE 查询，单独限流
+	movieSearchLimit := rateLimiter.LimitIf("movie_search", func(c *gin.Context) bool {
+		return c.Query("search") != ""
+	})
+	
+	// 请求体大小限制，批量与导入接口单独放宽
+	batchBody := middleware.MaxBodySize(cfg.MaxBatchBodySize)
+	importBody := middleware.MaxBodySize(cfg.MaxImportBodySize)
+	
+	// 开启后未验证邮箱的用户只能读取数据
+	verifiedEmail := middleware.RequireVerifiedEmail(cfg.RequireVerifiedEmail)
+	
+	// 特权操作（用户管理、删除数据、管理员接口）要求指定角色通过两步验证
+	privileged := middleware.RequireTwoFactor(cfg.TwoFactorRequiredRoles)
+	
+	// 接口默认禁止缓存；电影数据与调用者无关，允许浏览器和 CDN 缓存匿名请求的响应，列表还可在服务端缓存
+	// 服务端缓存与电影查询缓存共用命名空间，电影变化或统计重新计算后一起失效
+	publicCache := middleware.PublicCache(cfg.PublicCacheMaxAge, cfg.AuthRequired)
+	cachedList := middleware.ResponseCache(responseCache, service.MovieListCacheNamespace, cfg.ResponseCacheTTL)
+	cachedStats := middleware.ResponseCache(responseCache, service.MovieStatsCacheNamespace, cfg.ResponseCacheTTL)
+	
 	// API v1 路由组
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", middleware.NoStore(), middleware.MaxBodySize(cfg.MaxBodySize), middleware.Authenticate(authenticators...), middleware.AuditContext())
 	{
+		// 登录相关路由
+		auth := v1.Group("/auth", rateLimiter.Limit("auth"))
+		{
+			auth.GET("/oidc/login", authHandler.OIDCLogin)
+			auth.GET("/oidc/callback", authHandler.OIDCCallback)
+			auth.GET("/me", authHandler.Me)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/email/verification", authHandler.SendVerificationEmail)
+			auth.GET("/email/verify", authHandler.VerifyEmail)
+			auth.POST("/email/verify", authHandler.VerifyEmail)
+			auth.GET("/email/change/confirm", authHandler.ConfirmEmailChange)
+			auth.POST("/email/change/confirm", authHandler.ConfirmEmailChange)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/2fa/verify", authHandler.TwoFactorLogin)
+			auth.POST("/2fa/enroll", authHandler.EnrollTwoFactor)
+			auth.POST("/2fa/confirm", authHandler.ConfirmTwoFactor)
+			auth.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			auth.POST("/2fa/disable", authHandler.DisableTwoFactor)
+			auth.POST("/logout", authHandler.Logout)
+			auth.GET("/sessions", authHandler.GetSessions)
+			auth.DELETE("/sessions", authHandler.RevokeSessions)
+			auth.DELETE("/sessions/:id", authHandler.RevokeSession)
+		}
+		
+		// 实时事件流，按调用者的读取权限过滤资源；用户资源与用户路由一样要求特权角色通过两步验证
+		v1.GET("/events", rateLimiter.Limit("events"), middleware.RequireReadableResources(cfg.AuthRequired, cfg.TwoFactorRequiredRoles, []string{"users", "products", "movies"}, "users"), eventHandler.Stream)
+		
 		// 用户相关路由
-		users := v1.Group("/users")
+		users := v1.Group("/users", rateLimiter.Limit("users"), middleware.RequireResourceScope(cfg.AuthRequired, "users"), verifiedEmail, privileged)
 		{
-			users.POST("", userHandler.CreateUser)
+			users.POST("", idempotent, userHandler.CreateUser)
+			users.POST("/batch", batchBody, idempotent, userHandler.CreateUsers)
+			users.PUT("/batch", batchBody, userHandler.UpdateUsers)
+			users.DELETE("/batch", batchBody, userHandler.DeleteUsers)
 			users.GET("", userHandler.GetUsers)
+			users.GET("/trash", userHandler.GetDeletedUsers)
+			users.GET("/export", userHandler.ExportUsers)
 			users.GET("/:id", userHandler.GetUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+			users.PUT("/:id", ifMatch, userHandler.UpdateUser)
+			users.PATCH("/:id", ifMatch, userHandler.PatchUser)
+			users.DELETE("/:id", ifMatch, userHandler.DeleteUser)
+			users.POST("/:id/restore", userHandler.RestoreUser)
 		}
 		
 		// 产品相关路由
-		products := v1.Group("/products")
+		products := v1.Group("/products", rateLimiter.Limit("products"), middleware.RequireResourceScope(cfg.AuthRequired, "products"), verifiedEmail)
 		{
-			products.POST("", productHandler.CreateProduct)
+			products.POST("", idempotent, productHandler.CreateProduct)
+			products.POST("/batch", batchBody, idempotent, productHandler.CreateProducts)
+			products.PUT("/batch", batchBody, productHandler.UpdateProducts)
+			products.DELETE("/batch", privileged, batchBody, productHandler.DeleteProducts)
 			products.GET("", productHandler.GetProducts)
+			products.GET("/trash", productHandler.GetDeletedProducts)
+			products.GET("/export", productHandler.ExportProducts)
 			products.GET("/:id", productHandler.GetProduct)
-			products.PUT("/:id", productHandler.UpdateProduct)
-			products.DELETE("/:id", productHandler.DeleteProduct)
+			products.PUT("/:id", ifMatch, productHandler.UpdateProduct)
+			products.PATCH("/:id", ifMatch, productHandler.PatchProduct)
+			products.DELETE("/:id", privileged, ifMatch, productHandler.DeleteProduct)
+			products.POST("/:id/restore", productHandler.RestoreProduct)
 		}
 		
 		// 电影相关路由
-		movies := v1.Group("/movies")
+		movies := v1.Group("/movies", rateLimiter.Limit("movies"), middleware.RequireResourceScope(cfg.AuthRequired, "movies"), verifiedEmail)
+		{
+			movies.POST("", idempotent, movieHandler.CreateMovie)
+			movies.POST("/batch", batchBody, idempotent, movieHandler.CreateMovies)
+			movies.PUT("/batch", batchBody, movieHandler.UpdateMovies)
+			movies.DELETE("/batch", privileged, batchBody, movieHandler.DeleteMovies)
+			movies.GET("", movieSearchLimit, publicCache, cachedList, movieHandler.GetMovies)
+			movies.GET("/stats", publicCache, cachedStats, movieHandler.GetMovieStats)
+			movies.GET("/top-rated", publicCache, cachedList, movieHandler.GetTopRatedMovies)
+			movies.GET("/by-genre", publicCache, cachedList, movieHandler.GetMoviesByGenre)
+			movies.GET("/trash", movieHandler.GetDeletedMovies)
+			movies.GET("/export", movieHandler.ExportMovies)
+			movies.POST("/import", importBody, movieImportHandler.ImportMovies)
+			movies.GET("/import/:job_id", movieImportHandler.GetImportJob)
+			movies.POST("/video-checks", videoCheckHandler.StartVideoCheck)
+			movies.GET("/video-checks", videoCheckHandler.GetVideoChecks)
+			movies.GET("/:id", publicCache, movieHandler.GetMovie)
+			movies.PUT("/:id", ifMatch, movieHandler.UpdateMovie)
+			movies.PATCH("/:id", ifMatch, movieHandler.PatchMovie)
+			movies.DELETE("/:id", privileged, ifMatch, movieHandler.DeleteMovie)
+			movies.POST("/:id/restore", movieHandler.RestoreMovie)
+			movies.GET("/:id/revisions", movieHandler.GetMovieRevisions)
+			movies.GET("/:id/revisions/diff", movieHandler.DiffMovieRevisions)
+			movies.POST("/:id/revisions/:version/revert", ifMatch, movieHandler.RevertMovie)
+		}
+		
+		// 后台任务，管理员可见全部任务，其他调用者只能查看和取消自己创建的任务
+		jobs := v1.Group("/jobs", rateLimiter.Limit("jobs"), middleware.RequireAuthenticated(cfg.AuthRequired))
+		{
+			jobs.GET("", jobHandler.GetJobs)
+			jobs.GET("/:id", jobHandler.GetJob)
+			jobs.GET("/:id/download", jobHandler.DownloadExport)
+			jobs.POST("/:id/cancel", jobHandler.CancelJob)
+		}
+		
+		// 管理员路由
+		admin := v1.Group("/admin", rateLimiter.Limit("admin"), middleware.RequireScope(true, model.ScopeAdmin), privileged)
+		{
+			admin.DELETE("/users/:id", userHandler.PurgeUser)
+			admin.DELETE("/products/:id", productHandler.PurgeProduct)
+			admin.DELETE("/movies/:id", movieHandler.PurgeMovie)
+			admin.POST("/trash/purge", trashHandler.PurgeExpired)
+			admin.DELETE("/users/:id/2fa", authHandler.ResetTwoFactor)
+			admin.GET("/users/:id/sessions", authHandler.GetUserSessions)
+			admin.DELETE("/users/:id/sessions", authHandler.RevokeUserSessions)
+			admin.POST("/users/:id/unlock", authHandler.UnlockUser)
+			
+			admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+			admin.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+			admin.GET("/api-keys/:id", apiKeyHandler.GetAPIKey)
+			admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+			admin.POST("/api-keys/:id/rotate", apiKeyHandler.RotateAPIKey)
+			
+			admin.POST("/webhooks", webhookHandler.CreateWebhook)
+			admin.GET("/webhooks", webhookHandler.GetWebhooks)
+			admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+			admin.PATCH("/webhooks/:id", webhookHandler.UpdateWebhook)
+			admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			admin.POST("/webhooks/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+			admin.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+			admin.POST("/webhooks/:id/deliveries/:delivery_id/retry", webhookHandler.RetryDelivery)
+			
+			admin.POST("/jobs/:id/retry", jobHandler.RetryJob)
+			
+			admin.GET("/tasks", taskHandler.GetTasks)
+			admin.GET("/tasks/:name/runs", taskHandler.GetTaskRuns)
+			admin.POST("/tasks/:name/run", taskHandler.RunTask)
+		}
+		
+		// 审计日志（管理员）
+		audit := v1.Group("/audit", rateLimiter.Limit("admin"), middleware.RequireScope(true, model.ScopeAdmin), privileged)
 		{
-			movies.POST("", movieHandler.CreateMovie)
-			movies.GET("", movieHandler.GetMovies)
-			movies.GET("/stats", movieHandler.GetMovieStats)
-			movies.GET("/top-rated", movieHandler.GetTopRatedMovies)
-			movies.GET("/by-genre", movieHandler.GetMoviesByGenre)
-			movies.GET("/:id", movieHandler.GetMovie)
-			movies.PUT("/:id", movieHandler.UpdateMovie)
-			movies.DELETE("/:id", movieHandler.DeleteMovie)
+			audit.GET("", auditHandler.GetAuditLogs)
 		}
 	}
 }
\ No newline at end of file
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/mailer"
+	"topService/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// dummyPasswordHash 用户不存在时也做一次哈希比较，避免通过响应时间判断用户是否存在
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("topService"), bcrypt.DefaultCost)
+
+// AccountService 密码登录、邮箱验证与密码重置
+type AccountService struct {
+	db        *gorm.DB
+	mailer    mailer.Mailer
+	tokens    *userTokens
+	throttle  *LoginThrottleService
+	jobs      *JobService
+	verifyURL string
+	resetURL  string
+	changeURL string
+	verifyTTL time.Duration
+	resetTTL  time.Duration
+}
+
+func NewAccountService(db *gorm.DB, m mailer.Mailer, throttle *LoginThrottleService, jobs *JobService, cfg *config.Config) *AccountService {
+	return &AccountService{
+		db:        db,
+		mailer:    m,
+		tokens:    newUserTokens(db, cfg.SessionSecret),
+		throttle:  throttle,
+		jobs:      jobs,
+		verifyURL: cfg.EmailVerifyURL,
+		resetURL:  cfg.PasswordResetURL,
+		changeURL: cfg.EmailChangeURL,
+		verifyTTL: cfg.EmailVerifyTokenTTL,
+		resetTTL:  cfg.PasswordResetTokenTTL,
+	}
+}
+
+func (s *AccountService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// 邮件模板，同时作为 email.send 任务的邮件类型
+const (
+	emailVerify        = "verify_email"
+	emailResetPassword = "reset_password"
+	emailChange        = "change_email"        // 发往新邮箱的确认邮件
+	emailChangeNotice  = "change_email_notice" // 发往原邮箱的通知，不含令牌
+)
+
+// emailPayload email.send 任务参数；令牌在发送时签发，不写入任务参数
+type emailPayload struct {
+	Template string `json:"template"`
+	UserID   uint   `json:"user_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Locale   string `json:"locale"`
+}
+
+// emailResult email.send 任务结果，sent 为 false 表示用户已不满足发送条件
+type emailResult struct {
+	Sent bool `json:"sent"`
+}
+
+// Login 使用用户名或邮箱和密码登录，ip 为客户端地址，用于失败计数
+func (s *AccountService) Login(ctx context.Context, login, password, ip string) (*model.User, error) {
+	var user model.User
+	err := s.conn(ctx).Where("username = ? OR email = ?", login, login).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	
+	// 用户不存在时按登录名计数，与存在的用户表现一致
+	account := throttleLoginKey(login)
+	if user.ID != 0 {
+		account = throttleUserKey(user.ID)
+	}
+	if err := s.throttle.Check(ctx, account, throttleIPKey(ip)); err != nil {
+		return nil, err
+	}
+	
+	hash := []byte(user.PasswordHash)
+	if user.ID == 0 || len(hash) == 0 {
+		hash = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || user.ID == 0 || user.PasswordHash == "" {
+		if err := s.throttle.Fail(ctx, account, throttleIPKey(ip)); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidLogin
+	}
+	if user.Status != 1 {
+		return nil, ErrUserDisabled
+	}
+	
+	if err := s.throttle.Reset(ctx, account); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SendVerificationEmail 为用户当前邮箱创建验证邮件的发送任务，已验证时不发送
+func (s *AccountService) SendVerificationEmail(ctx context.Context, userID uint, locale string) (bool, error) {
+	var user model.User
+	if err := s.conn(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, errors.New("用户不存在")
+		}
+		return false, err
+	}
+	if user.EmailVerifiedAt != nil {
+		return false, nil
+	}
+	
+	_, err := s.jobs.Enqueue(ctx, model.JobSendEmail, &emailPayload{Template: emailVerify, UserID: user.ID, Locale: locale}, nil)
+	return err == nil, err
+}
+
+// VerifyEmail 使用验证令牌标记邮箱已验证；令牌签发后邮箱被修改时令牌失效
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	var user *model.User
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var claims *userTokenClaims
+		var err error
+		if user, claims, err = s.tokens.consume(ctx, token, model.TokenPurposeVerifyEmail); err != nil {
+			return err
+		}
+		if claims.Email != user.Email {
+			return ErrInvalidUserToken
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		
+		before := user.ToResponse()
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		user.Version++
+		if err := s.conn(ctx).Model(user).Updates(map[string]interface{}{
+			"email_verified_at": now,
+			"version":           user.Version,
+		}).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse())
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return user, nil
+}
+
+// RequestPasswordReset 创建密码重置邮件的发送任务
+// 邮箱是否存在在任务中判断，不存在、未验证或用户已禁用时不发送，避免通过响应内容或时间探测邮箱是否注册
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email, locale string) error {
+	_, err := s.jobs.Enqueue(ctx, model.JobSendEmail, &emailPayload{Template: emailResetPassword, Email: email, Locale: locale}, nil)
+	return err
+}
+
+// ResetPassword 使用重置令牌设置新密码，同时作废该用户其他未使用的重置令牌并吊销全部会话
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) (*model.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	
+	var user *model.User
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var claims *userTokenClaims
+		var err error
+		if user, claims, err = s.tokens.consume(ctx, token, model.TokenPurposeResetPassword); err != nil {
+			return err
+		}
+		// 令牌签发后邮箱被修改时令牌失效
+		if claims.Email != user.Email {
+			return ErrInvalidUserToken
+		}
+		if user.Status != 1 {
+			return ErrUserDisabled
+		}
+		
+		// 响应格式不含密码哈希，审计日志只记录这次修改本身
+		before := user.ToResponse()
+		now := time.Now()
+		if err := s.conn(ctx).Model(user).Updates(map[string]interface{}{
+			"password_hash": string(hash),
+			"version":       user.Version + 1,
+		}).Error; err != nil {
+			return err
+		}
+		user.Version++
+		if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse()); err != nil {
+			return err
+		}
+		
+		// 密码可能已泄露，退出所有已登录的设备
+		if _, err := revokeSessions(s.conn(ctx), user.ID, 0); err != nil {
+			return err
+		}
+		
+		return s.conn(ctx).Model(&model.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, model.TokenPurposeResetPassword).
+			Update("used_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return user, nil
+}
+
+// ConfirmEmailChange 使用发往新邮箱的令牌把待确认的邮箱设为当前邮箱，新邮箱视为已验证
+// 令牌签发后再次修改邮箱或新邮箱已被他人使用时失败
+func (s *AccountService) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	var user *model.User
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var claims *userTokenClaims
+		var err error
+		if user, claims, err = s.tokens.consume(ctx, token, model.TokenPurposeChangeEmail); err != nil {
+			return err
+		}
+		if user.PendingEmail == "" || claims.Email != user.PendingEmail {
+			return ErrInvalidUserToken
+		}
+		
+		var count int64
+		if err := s.conn(ctx).Model(&model.User{}).
+			Where("email = ? AND id <> ?", user.PendingEmail, user.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+		
+		before := user.ToResponse()
+		now := time.Now()
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+		user.EmailVerifiedAt = &now
+		user.Version++
+		if err := s.conn(ctx).Model(user).Updates(map[string]interface{}{
+			"email":             user.Email,
+			"pending_email":     "",
+			"email_verified_at": now,
+			"version":           user.Version,
+		}).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse())
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return user, nil
+}
+
+// RunEmailJob 执行 email.send 任务：签发令牌并发送邮件，用户已不满足发送条件时跳过
+func (s *AccountService) RunEmailJob(ctx context.Context, run *JobRun) (interface{}, error) {
+	var payload emailPayload
+	if err := run.Bind(&payload); err != nil {
+		return nil, err
+	}
+	
+	var user model.User
+	var err error
+	switch payload.Template {
+	case emailVerify, emailChange, emailChangeNotice:
+		err = s.conn(ctx).First(&user, payload.UserID).Error
+	case emailResetPassword:
+		err = s.conn(ctx).Where("email = ?", payload.Email).First(&user).Error
+	default:
+		return nil, PermanentJobError(fmt.Errorf("未知的邮件类型: %s", payload.Template))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &emailResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	switch payload.Template {
+	case emailVerify:
+		if user.EmailVerifiedAt != nil {
+			return &emailResult{}, nil
+		}
+		token, err := s.tokens.issue(ctx, &user, user.Email, model.TokenPurposeVerifyEmail, s.verifyTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &emailResult{Sent: true}, s.sendLink(ctx, emailVerify, payload.Locale, &user, user.Email, s.verifyURL, token, s.verifyTTL)
+	
+	case emailChange:
+		// 已确认或又改成了其他邮箱时不再发送
+		if user.PendingEmail == "" || user.PendingEmail != payload.Email {
+			return &emailResult{}, nil
+		}
+		token, err := s.tokens.issue(ctx, &user, user.PendingEmail, model.TokenPurposeChangeEmail, s.verifyTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &emailResult{Sent: true}, s.sendLink(ctx, emailChange, payload.Locale, &user, user.PendingEmail, s.changeURL, token, s.verifyTTL)
+	
+	case emailChangeNotice:
+		return &emailResult{Sent: true}, s.send(ctx, emailChangeNotice, payload.Locale, user.Email, map[string]interface{}{
+			"Username": user.Username,
+			"NewEmail": payload.Email,
+		})
+	}
+	
+	// 未验证的邮箱不能证明归属，不发送重置邮件
+	if user.Status != 1 || user.EmailVerifiedAt == nil {
+		return &emailResult{}, nil
+	}
+	token, err := s.tokens.issue(ctx, &user, user.Email, model.TokenPurposeResetPassword, s.resetTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &emailResult{Sent: true}, s.sendLink(ctx, emailResetPassword, payload.Locale, &user, user.Email, s.resetURL, token, s.resetTTL)
+}
+
+// sendLink 发送带令牌链接的邮件到 to
+func (s *AccountService) sendLink(ctx context.Context, name, locale string, user *model.User, to, baseURL, token string, ttl time.Duration) error {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	
+	return s.send(ctx, name, locale, to, map[string]interface{}{
+		"Username":  user.Username,
+		"URL":       link.String(),
+		"ExpiresIn": mailer.FormatDuration(ttl, locale),
+	})
+}
+
+// send 渲染并发送邮件
+func (s *AccountService) send(ctx context.Context, name, locale, to string, data map[string]interface{}) error {
+	msg, err := mailer.Render(name, locale, to, data)
+	if err != nil {
+		return err
+	}
+	
+	return s.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// apiKeyPrefix 密钥统一前缀，便于在日志和代码扫描中识别泄露的密钥
+	apiKeyPrefix = "tsk_"
+	// apiKeyDisplayLength 保存用于展示的密钥前缀长度
+	apiKeyDisplayLength = 12
+	// apiKeyTouchInterval 最近使用时间的更新间隔，避免每次请求都写库
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+func (s *APIKeyService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// hashAPIKey 计算密钥哈希；密钥为高熵随机串，直接使用 SHA-256 即可
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey 生成新的明文密钥
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// validateScopes 校验权限范围并去重
+func validateScopes(scopes []string) (string, error) {
+	seen := make(map[string]bool, len(scopes))
+	list := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !model.ValidScope(scope) {
+			return "", fmt.Errorf("未知的权限范围: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			list = append(list, scope)
+		}
+	}
+	return strings.Join(list, ","), nil
+}
+
+// newAPIKey 用明文密钥构造记录
+func newAPIKey(key, name, scopes, createdBy string, expiresAt *time.Time) *model.APIKey {
+	// 非本服务生成的密钥（如初始密钥）可能较短，只保留前 4 位避免泄露
+	length := apiKeyDisplayLength
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		length = 4
+	}
+	prefix := key
+	if len(prefix) > length {
+		prefix = prefix[:length]
+	}
+	return &model.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// CreateAPIKey 创建 API Key，返回记录和只在此时可见的明文密钥
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *model.APIKeyCreateRequest, createdBy string) (*model.APIKey, string, error) {
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", errors.New("过期时间必须晚于当前时间")
+	}
+	
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	
+	apiKey := newAPIKey(key, req.Name, scopes, createdBy, req.ExpiresAt)
+	if err := s.conn(ctx).Create(apiKey).Error; err != nil {
+		return nil, "", err
+	}
+	
+	return apiKey, key, nil
+}
+
+// EnsureBootstrapKey 尚无任何可用密钥时，用给定明文创建拥有全部权限的初始密钥
+func (s *APIKeyService) EnsureBootstrapKey(ctx context.Context, key string) (bool, error) {
+	var count int64
+	err := s.conn(ctx).Model(&model.APIKey{}).
+		Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return false, err
+	}
+	
+	apiKey := newAPIKey(key, "bootstrap", model.ScopeAll, "system", nil)
+	if err := s.conn(ctx).Create(apiKey).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAPIKeyByID 根据ID获取 API Key
+func (s *APIKeyService) GetAPIKeyByID(ctx context.Context, id uint) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	if err := s.conn(ctx).First(&apiKey, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API Key不存在")
+		}
+		return nil, err
+	}
+	
+	return &apiKey, nil
+}
+
+// GetAPIKeys 获取 API Key 列表，includeRevoked 为 true 时包含已吊销的密钥
+func (s *APIKeyService) GetAPIKeys(ctx context.Context, page, pageSize int, includeRevoked bool) ([]*model.APIKey, int64, error) {
+	var apiKeys []*model.APIKey
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.APIKey{})
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&apiKeys).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return apiKeys, total, nil
+}
+
+// RevokeAPIKey 吊销 API Key，重复吊销不报错
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id uint) (*model.APIKey, error) {
+	apiKey, err := s.GetAPIKeyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return apiKey, nil
+	}
+	
+	now := time.Now()
+	if err := s.conn(ctx).Model(apiKey).Update("revoked_at", now).Error; err != nil {
+		return nil, err
+	}
+	apiKey.RevokedAt = &now
+	
+	return apiKey, nil
+}
+
+// RotateAPIKey 轮换 API Key：以相同名称、权限和过期时间签发新密钥
+// grace 大于 0 时旧密钥在宽限期后过期，便于调用方平滑切换；否则立即吊销
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id uint, grace time.Duration, createdBy string) (*model.APIKey, string, error) {
+	var rotated *model.APIKey
+	var key string
+	
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var old model.APIKey
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&old, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("API Key不存在")
+			}
+			return err
+		}
+		
+		now := time.Now()
+		if !old.Active(now) {
+			return ErrInvalidAPIKey
+		}
+		
+		var err error
+		if key, err = generateAPIKey(); err != nil {
+			return err
+		}
+		rotated = newAPIKey(key, old.Name, old.Scopes, createdBy, old.ExpiresAt)
+		if err := s.conn(ctx).Create(rotated).Error; err != nil {
+			return err
+		}
+		
+		if grace > 0 {
+			expiresAt := now.Add(grace)
+			if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+				return s.conn(ctx).Model(&old).Update("expires_at", expiresAt).Error
+			}
+			return nil
+		}
+		return s.conn(ctx).Model(&old).Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	
+	return rotated, key, nil
+}
+
+// Authenticate 校验明文密钥并记录最近使用时间
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	if err := s.conn(ctx).Where("key_hash = ?", hashAPIKey(key)).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	
+	now := time.Now()
+	if !apiKey.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.conn(ctx).Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+	
+	return &apiKey, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type auditContextKey struct{}
+
+// WithAuditMeta 把请求来源写入 ctx，之后的数据修改都以此记录审计日志
+func WithAuditMeta(ctx context.Context, meta model.AuditMeta) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, meta)
+}
+
+// auditMetaFrom 返回 ctx 中的请求来源，没有时记为匿名
+func auditMetaFrom(ctx context.Context) model.AuditMeta {
+	meta, _ := ctx.Value(auditContextKey{}).(model.AuditMeta)
+	if meta.Actor == "" {
+		meta.Actor = model.AuditAnonymous
+	}
+	return meta
+}
+
+// auditIgnoredFields 不计入差异的字段：每次修改都会变化，或不是业务数据
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// auditSnapshot 把实体的响应格式展开为字段名 -> 值，v 为 nil 表示实体不存在
+// 使用响应格式而不是数据库模型，避免把密钥哈希等敏感字段写入审计日志
+func auditSnapshot(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// auditDiff 比较两个快照，返回值有变化的字段
+func auditDiff(before, after map[string]interface{}) map[string]model.FieldChange {
+	changes := make(map[string]model.FieldChange)
+	for name, value := range before {
+		if !auditIgnoredFields[name] && !reflect.DeepEqual(value, after[name]) {
+			changes[name] = model.FieldChange{Before: value, After: after[name]}
+		}
+	}
+	for name, value := range after {
+		if _, ok := before[name]; !ok && !auditIgnoredFields[name] && value != nil {
+			changes[name] = model.FieldChange{Before: nil, After: value}
+		}
+	}
+	return changes
+}
+
+// auditChanges 比较修改前后实体的响应格式，返回有变化的字段；实体不存在时传 nil
+func auditChanges(before, after interface{}) (map[string]model.FieldChange, error) {
+	beforeFields, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	return auditDiff(beforeFields, afterFields), nil
+}
+
+// newAuditLog 构造审计日志，操作者等请求来源取自 ctx
+func newAuditLog(ctx context.Context, action, entityType string, entityID uint, changes map[string]model.FieldChange) (*model.AuditLog, error) {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	
+	meta := auditMetaFrom(ctx)
+	return &model.AuditLog{
+		Actor:      meta.Actor,
+		RequestID:  meta.RequestID,
+		IP:         meta.IP,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    string(data),
+	}, nil
+}
+
+// AuditService 查询审计日志
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// GetAuditLogs 按条件分页查询审计日志，最新的在前
+func (s *AuditService) GetAuditLogs(ctx context.Context, filter *model.AuditLogFilter, page, pageSize int) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var total int64
+	
+	query := database.Conn(ctx, s.db).Model(&model.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return logs, total, nil
+}
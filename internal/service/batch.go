@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"topService/internal/database"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// batchSize 批量插入时每批的记录数
+	batchSize = 100
+	// exportBatchSize 导出时每批读取的记录数
+	exportBatchSize = 500
+)
+
+// runBatch 逐条执行批量操作，返回与条目一一对应的错误（nil 表示成功）
+// atomic 为 true 时所有条目在同一事务中执行，任一失败即整体回滚，其余条目记为 ErrBatchRolledBack
+func runBatch(ctx context.Context, db *gorm.DB, n int, atomic bool, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	if !atomic {
+		for i := 0; i < n; i++ {
+			errs[i] = fn(ctx, i)
+		}
+		return errs
+	}
+	
+	failed := -1
+	err := database.Transaction(ctx, db, func(ctx context.Context) error {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrBatchRolledBack
+		}
+		if failed >= 0 {
+			errs[failed] = err
+		}
+	}
+	
+	return errs
+}
+
+// insertBatch 分批插入 n 条记录，insert 负责插入下标 [from, to) 的记录（及其审计日志）
+// atomic 为 true 时在同一事务中插入，失败时所有条目返回同一错误；
+// 否则每批在单独的事务中插入，某批失败时逐条重试以定位失败的条目
+func insertBatch(ctx context.Context, db *gorm.DB, n int, atomic bool, insert func(db *gorm.DB, from, to int) error) []error {
+	errs := make([]error, n)
+	if atomic {
+		err := database.Transaction(ctx, db, func(ctx context.Context) error {
+			return insert(database.Conn(ctx, db), 0, n)
+		})
+		if err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+	
+	for from := 0; from < n; from += batchSize {
+		to := from + batchSize
+		if to > n {
+			to = n
+		}
+		
+		if err := insertTx(ctx, db, insert, from, to); err == nil {
+			continue
+		}
+		for i := from; i < to; i++ {
+			errs[i] = insertTx(ctx, db, insert, i, i+1)
+		}
+	}
+	
+	return errs
+}
+
+// insertTx 在事务中插入下标 [from, to) 的记录，记录与审计日志同时提交或回滚
+func insertTx(ctx context.Context, db *gorm.DB, insert func(db *gorm.DB, from, to int) error, from, to int) error {
+	return database.Transaction(ctx, db, func(ctx context.Context) error {
+		return insert(database.Conn(ctx, db), from, to)
+	})
+}
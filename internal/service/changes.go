@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// eventActions 审计动作对应的领域事件动作
+var eventActions = map[string]string{
+	model.AuditActionCreate:  "created",
+	model.AuditActionUpdate:  "updated",
+	model.AuditActionDelete:  "deleted",
+	model.AuditActionRestore: "restored",
+	model.AuditActionPurge:   "purged",
+}
+
+// newOutboxEvent 构造领域事件，object 为事件涉及的资源（响应格式）
+func newOutboxEvent(eventType, resourceType string, resourceID uint, object interface{}, changes map[string]model.FieldChange) (*model.OutboxEvent, error) {
+	payload, err := json.Marshal(&model.EventData{Object: object, Changes: changes})
+	if err != nil {
+		return nil, err
+	}
+	return &model.OutboxEvent{
+		Type:         eventType,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Payload:      string(payload),
+	}, nil
+}
+
+// recordEvent 通过 db 写入领域事件（事务性发件箱），db 应为执行修改的事务连接，修改回滚时事件一同回滚
+func recordEvent(db *gorm.DB, eventType, resourceType string, resourceID uint, object interface{}, changes map[string]model.FieldChange) error {
+	event, err := newOutboxEvent(eventType, resourceType, resourceID, object, changes)
+	if err != nil {
+		return err
+	}
+	return db.Create(event).Error
+}
+
+// recordChange 记录一次数据修改：在 db 所在事务中写入审计日志和对应的领域事件（如 movie.updated）
+// before/after 为修改前后实体的响应格式，不存在时传 nil
+func recordChange(ctx context.Context, db *gorm.DB, action, entityType string, entityID uint, before, after interface{}) error {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		return err
+	}
+	
+	entry, err := newAuditLog(ctx, action, entityType, entityID, changes)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(entry).Error; err != nil {
+		return err
+	}
+	
+	object := after
+	if object == nil {
+		object = before
+	}
+	return recordEvent(db, entityType+"."+eventActions[action], entityType, entityID, object, changes)
+}
+
+// recordCreates 为批量新建的 n 个实体写入审计日志和 created 事件，entity 返回第 i 个实体的 ID 和响应格式
+func recordCreates(ctx context.Context, db *gorm.DB, entityType string, n int, entity func(i int) (uint, interface{})) error {
+	entries := make([]*model.AuditLog, n)
+	events := make([]*model.OutboxEvent, n)
+	for i := 0; i < n; i++ {
+		id, response := entity(i)
+		changes, err := auditChanges(nil, response)
+		if err != nil {
+			return err
+		}
+		if entries[i], err = newAuditLog(ctx, model.AuditActionCreate, entityType, id, changes); err != nil {
+			return err
+		}
+		if events[i], err = newOutboxEvent(entityType+"."+eventActions[model.AuditActionCreate], entityType, id, response, changes); err != nil {
+			return err
+		}
+	}
+	
+	if err := db.CreateInBatches(entries, batchSize).Error; err != nil {
+		return err
+	}
+	return db.CreateInBatches(events, batchSize).Error
+}
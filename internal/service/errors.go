@@ -0,0 +1,98 @@
+package service
+
+import "errors"
+
+var (
+	// ErrVersionConflict 资源版本与请求期望的版本不一致（乐观锁冲突）
+	ErrVersionConflict = errors.New("资源已被其他人修改，请刷新后重试")
+	
+	// ErrBatchRolledBack 批量操作中其他条目失败导致本条目随事务回滚
+	ErrBatchRolledBack = errors.New("批量操作中存在失败条目，已整体回滚")
+	
+	// ErrIdempotencyInProgress 同一幂等键的请求正在处理中
+	ErrIdempotencyInProgress = errors.New("相同幂等键的请求正在处理中")
+	
+	// ErrIdempotencyKeyMismatch 幂等键已用于内容不同的请求
+	ErrIdempotencyKeyMismatch = errors.New("幂等键已用于不同的请求内容")
+	
+	// ErrInvalidAPIKey API Key 不存在、已吊销或已过期
+	ErrInvalidAPIKey = errors.New("API Key无效或已过期")
+	
+	// ErrInvalidSession 会话令牌无效、已过期或用户已被禁用
+	ErrInvalidSession = errors.New("登录已失效，请重新登录")
+	
+	// ErrUserDisabled 用户已被禁用
+	ErrUserDisabled = errors.New("用户已被禁用")
+	
+	// ErrInvalidLoginState OIDC 登录状态无效、过期或与回调不匹配
+	ErrInvalidLoginState = errors.New("登录状态无效或已过期，请重新登录")
+	
+	// ErrEmailNotVerified IdP 未声明邮箱已验证，不能据此关联已有用户
+	ErrEmailNotVerified = errors.New("身份提供方返回的邮箱未验证")
+	
+	// ErrInvalidLogin 用户名或密码错误
+	ErrInvalidLogin = errors.New("用户名或密码错误")
+	
+	// ErrInvalidUserToken 邮件中的链接无效、已过期或已被使用
+	ErrInvalidUserToken = errors.New("链接无效、已过期或已被使用")
+	
+	// ErrEmailChangeForbidden 未认证的请求不能修改邮箱
+	ErrEmailChangeForbidden = errors.New("登录后才能修改邮箱")
+	
+	// ErrEmailTaken 新邮箱已被其他用户使用
+	ErrEmailTaken = errors.New("邮箱已被其他用户使用")
+	
+	// ErrTwoFactorAlreadyEnabled 已启用两步验证，需先关闭才能重新绑定
+	ErrTwoFactorAlreadyEnabled = errors.New("已启用两步验证")
+	
+	// ErrTwoFactorNotEnabled 未启用或未开始绑定两步验证
+	ErrTwoFactorNotEnabled = errors.New("未启用两步验证")
+	
+	// ErrInvalidTwoFactorCode 动态码或恢复码错误，或动态码已被使用
+	ErrInvalidTwoFactorCode = errors.New("验证码错误")
+	
+	// ErrInvalidTwoFactorChallenge 登录第二步令牌无效、过期或错误次数过多
+	ErrInvalidTwoFactorChallenge = errors.New("两步验证已失效，请重新登录")
+	
+	// ErrLoginThrottled 登录失败次数过多，具体等待时间见 *LoginThrottledError
+	ErrLoginThrottled = errors.New("登录失败次数过多，请稍后重试")
+	
+	// ErrInsufficientStock 库存不足或产品不存在
+	ErrInsufficientStock = errors.New("库存不足")
+	
+	// ErrRevisionNotFound 电影不存在该历史版本
+	ErrRevisionNotFound = errors.New("电影版本不存在")
+	
+	// ErrWebhookNotFound Webhook 不存在
+	ErrWebhookNotFound = errors.New("Webhook不存在")
+	
+	// ErrDeliveryNotFound 投递记录不存在
+	ErrDeliveryNotFound = errors.New("投递记录不存在")
+	
+	// ErrDeliveryPending 投递记录仍在队列中，无需重新投递
+	ErrDeliveryPending = errors.New("投递记录仍在等待投递")
+	
+	// ErrEventBrokerClosed 服务正在停机，不再接受新的事件订阅
+	ErrEventBrokerClosed = errors.New("服务正在停止")
+	
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("任务不存在")
+	
+	// ErrJobNotCancelable 任务已开始执行或已结束，不能取消
+	ErrJobNotCancelable = errors.New("只能取消等待执行的任务")
+	
+	// ErrJobNotRetryable 任务未失败也未取消，不能重试
+	ErrJobNotRetryable = errors.New("只能重试失败或已取消的任务")
+	
+	// ErrInvalidExport 导出格式、资源或字段无效
+	ErrInvalidExport = errors.New("导出参数无效")
+	
+	// ErrExportNotReady 导出任务尚未成功完成，没有可下载的文件
+	ErrExportNotReady = errors.New("导出任务尚未完成")
+	
+	// ErrTaskNotFound 定时任务不存在
+	ErrTaskNotFound = errors.New("定时任务不存在")
+	
+	// ErrTaskRunning 定时任务正在某个实例上执行
+	ErrTaskRunning = errors.New("定时任务正在执行")
+)
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// eventBatchSize 每次从发件箱读取的事件数
+	eventBatchSize = 500
+	
+	// eventGapGrace 事件 ID 出现空洞时等待的时长
+	// 并发事务可能晚于 ID 更大的事务提交，等待期内暂缓推送其后的事件以保证按 ID 顺序推送；超时后视为回滚留下的空洞
+	eventGapGrace = 5 * time.Second
+	
+	// eventSubscriberBuffer 每个订阅的缓冲区大小，缓冲区满时断开该订阅，由客户端凭 Last-Event-ID 续传
+	eventSubscriberBuffer = 256
+)
+
+// EventSubscription 一个实时事件订阅
+type EventSubscription struct {
+	// Events 按 ID 递增顺序推送的事件，取消订阅或消费过慢被断开时关闭
+	Events <-chan *model.OutboxEvent
+	
+	// Cursor 订阅开始时已推送的最大事件 ID，此后推送的事件 ID 都大于它
+	Cursor uint
+	
+	events    chan *model.OutboxEvent
+	resources map[string]bool
+}
+
+// EventBroker 轮询发件箱中已提交的领域事件，按 ID 顺序推送给进程内的订阅者
+// 事件来自共享的数据库，多实例部署时每个实例都能收到其他实例产生的事件
+type EventBroker struct {
+	db *gorm.DB
+	
+	mu          sync.Mutex
+	ready       bool
+	closed      bool
+	cursor      uint
+	subscribers map[*EventSubscription]struct{}
+	
+	// 以下字段只由 Poll 访问
+	pending  map[uint]*model.OutboxEvent
+	gapSince time.Time
+}
+
+func NewEventBroker(db *gorm.DB) *EventBroker {
+	return &EventBroker{
+		db:          db,
+		subscribers: make(map[*EventSubscription]struct{}),
+		pending:     make(map[uint]*model.OutboxEvent),
+	}
+}
+
+// conn 事件流读取主库，避免从库延迟造成的乱序
+func (b *EventBroker) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, b.db).Clauses(dbresolver.Write)
+}
+
+// init 以发件箱当前最大的事件 ID 作为起点，调用方需持有 mu
+func (b *EventBroker) init(ctx context.Context) error {
+	if b.ready {
+		return nil
+	}
+	
+	var maxID uint
+	if err := b.conn(ctx).Model(&model.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return err
+	}
+	b.cursor = maxID
+	b.ready = true
+	return nil
+}
+
+// Subscribe 订阅 resources 类型资源的事件，resources 为空时订阅全部
+func (b *EventBroker) Subscribe(ctx context.Context, resources []string) (*EventSubscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	if b.closed {
+		return nil, ErrEventBrokerClosed
+	}
+	if err := b.init(ctx); err != nil {
+		return nil, err
+	}
+	
+	events := make(chan *model.OutboxEvent, eventSubscriberBuffer)
+	sub := &EventSubscription{
+		Events:    events,
+		Cursor:    b.cursor,
+		events:    events,
+		resources: make(map[string]bool, len(resources)),
+	}
+	for _, resource := range resources {
+		sub.resources[resource] = true
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (b *EventBroker) Unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Close 断开全部订阅并拒绝新的订阅，服务停机时调用，客户端凭 Last-Event-ID 重连后续传
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// GetEvents 按 ID 顺序读取 (after, until] 范围内 resources 类型资源的事件，用于断线续传
+func (b *EventBroker) GetEvents(ctx context.Context, after, until uint, resources []string, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	query := b.conn(ctx).Where("id > ? AND id <= ?", after, until)
+	if len(resources) > 0 {
+		query = query.Where("resource_type IN ?", resources)
+	}
+	if err := query.Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Poll 读取新提交的事件并推送给订阅者
+func (b *EventBroker) Poll(ctx context.Context) error {
+	b.mu.Lock()
+	err := b.init(ctx)
+	cursor := b.cursor
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	
+	var events []*model.OutboxEvent
+	if err := b.conn(ctx).Where("id > ?", cursor).Order("id").Limit(eventBatchSize).Find(&events).Error; err != nil {
+		return err
+	}
+	for _, event := range events {
+		b.pending[event.ID] = event
+	}
+	
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	for len(b.pending) > 0 {
+		next := b.cursor + 1
+		if event, ok := b.pending[next]; ok {
+			delete(b.pending, next)
+			b.cursor = next
+			b.gapSince = time.Time{}
+			b.publish(event)
+			continue
+		}
+		
+		// 下一个 ID 尚未出现，等待可能仍未提交的事务，超时后跳过空洞
+		if b.gapSince.IsZero() {
+			b.gapSince = time.Now()
+		}
+		if time.Since(b.gapSince) < eventGapGrace {
+			break
+		}
+		next = 0
+		for id := range b.pending {
+			if next == 0 || id < next {
+				next = id
+			}
+		}
+		b.cursor = next - 1
+		b.gapSince = time.Time{}
+	}
+	
+	return nil
+}
+
+// publish 把事件推送给订阅了该资源的订阅者，缓冲区已满的订阅被断开，调用方需持有 mu
+func (b *EventBroker) publish(event *model.OutboxEvent) {
+	for sub := range b.subscribers {
+		if len(sub.resources) > 0 && !sub.resources[event.ResourceType] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Run 按 interval 轮询发件箱，ctx 取消时退出；interval 不大于 0 时不启动
+func (b *EventBroker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Poll(ctx); err != nil {
+				log.Printf("Failed to poll events: %v", err)
+			}
+		}
+	}
+}
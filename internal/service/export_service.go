@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"topService/internal/model"
+	"topService/internal/tabular"
+)
+
+// exportSource 可导出资源的响应类型和分批读取方式，each 把每批记录转换为响应结构体指针交给 fn
+type exportSource struct {
+	responseType reflect.Type
+	each         func(ctx context.Context, fn func(responses []interface{}) error) error
+}
+
+// ExportService 异步导出，由 export 后台任务把数据写入 EXPORT_DIR 下的文件
+type ExportService struct {
+	dir            string
+	jobService     *JobService
+	userService    *UserService
+	productService *ProductService
+	movieService   *MovieService
+}
+
+func NewExportService(dir string, jobService *JobService, userService *UserService, productService *ProductService, movieService *MovieService) *ExportService {
+	return &ExportService{
+		dir:            dir,
+		jobService:     jobService,
+		userService:    userService,
+		productService: productService,
+		movieService:   movieService,
+	}
+}
+
+// StartExport 校验导出参数并创建导出任务
+func (s *ExportService) StartExport(ctx context.Context, req *model.ExportRequest) (*model.Job, error) {
+	if _, err := tabular.ParseFormat(req.Format); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	source, err := s.source(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if _, err := tabular.SelectColumns(source.responseType, req.Fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	
+	return s.jobService.Enqueue(ctx, model.JobExport, req, nil)
+}
+
+// source 按资源名确定导出的响应类型和读取方式
+func (s *ExportService) source(req *model.ExportRequest) (*exportSource, error) {
+	switch req.Resource {
+	case "users":
+		return &exportSource{
+			responseType: reflect.TypeOf(model.UserResponse{}),
+			each: func(ctx context.Context, fn func(responses []interface{}) error) error {
+				return s.userService.ExportUsers(ctx, req.Keyword, req.IncludeDeleted, func(users []*model.User) error {
+					responses := make([]interface{}, len(users))
+					for i, user := range users {
+						responses[i] = user.ToResponse()
+					}
+					return fn(responses)
+				})
+			},
+		}, nil
+	case "products":
+		return &exportSource{
+			responseType: reflect.TypeOf(model.ProductResponse{}),
+			each: func(ctx context.Context, fn func(responses []interface{}) error) error {
+				return s.productService.ExportProducts(ctx, req.Keyword, req.Category, req.IncludeDeleted, func(products []*model.Product) error {
+					responses := make([]interface{}, len(products))
+					for i, product := range products {
+						responses[i] = product.ToResponse()
+					}
+					return fn(responses)
+				})
+			},
+		}, nil
+	case "movies":
+		return &exportSource{
+			responseType: reflect.TypeOf(model.MovieResponse{}),
+			each: func(ctx context.Context, fn func(responses []interface{}) error) error {
+				return s.movieService.ExportMovies(ctx, req.Keyword, req.Genre, req.IncludeDeleted, func(movies []*model.Movie) error {
+					responses := make([]interface{}, len(movies))
+					for i, movie := range movies {
+						responses[i] = movie.ToResponse()
+					}
+					return fn(responses)
+				})
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("未知的导出资源: %s", req.Resource)
+	}
+}
+
+// RunExportJob 执行 export 任务：先写入临时文件，全部写完后再改名，失败时不留下不完整的文件
+func (s *ExportService) RunExportJob(ctx context.Context, run *JobRun) (interface{}, error) {
+	var req model.ExportRequest
+	if err := run.Bind(&req); err != nil {
+		return nil, err
+	}
+	format, err := tabular.ParseFormat(req.Format)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+	source, err := s.source(&req)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+	columns, err := tabular.SelectColumns(source.responseType, req.Fields)
+	if err != nil {
+		return nil, PermanentJobError(err)
+	}
+	
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	path := s.path(run.Job.ID, format)
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	defer file.Close()
+	
+	writer, err := tabular.NewWriter(file, format, columns.Names)
+	if err != nil {
+		return nil, err
+	}
+	
+	rows := 0
+	err = source.each(ctx, func(responses []interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, response := range responses {
+			if err := writer.WriteRow(columns.Values(response)); err != nil {
+				return err
+			}
+		}
+		rows += len(responses)
+		return run.Progress(ctx, rows, 0, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	
+	if err := run.Progress(ctx, rows, rows, nil); err != nil {
+		return nil, err
+	}
+	return &model.ExportResult{
+		Filename:    fmt.Sprintf("%s-%s.%s", req.Resource, run.Job.CreatedAt.Format("20060102150405"), format),
+		Format:      string(format),
+		ContentType: tabular.ContentType(format),
+		Rows:        rows,
+	}, nil
+}
+
+// OpenExport 返回已完成的导出任务的文件路径和结果
+func (s *ExportService) OpenExport(job *model.Job) (string, *model.ExportResult, error) {
+	if job.Type != model.JobExport {
+		return "", nil, ErrJobNotFound
+	}
+	if job.Status != model.JobStatusSucceeded {
+		return "", nil, ErrExportNotReady
+	}
+	
+	var result model.ExportResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		return "", nil, err
+	}
+	path := s.path(job.ID, tabular.Format(result.Format))
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, err
+	}
+	return path, &result, nil
+}
+
+// RemoveExport 删除导出任务的文件，任务记录被清理前调用
+func (s *ExportService) RemoveExport(job *model.Job) {
+	var result model.ExportResult
+	if job.Result == "" || json.Unmarshal([]byte(job.Result), &result) != nil {
+		return
+	}
+	if err := os.Remove(s.path(job.ID, tabular.Format(result.Format))); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove export file of job %d: %v", job.ID, err)
+	}
+}
+
+// path 导出文件路径：EXPORT_DIR/<任务ID>.<格式>
+func (s *ExportService) path(jobID uint, format tabular.Format) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d.%s", jobID, format))
+}
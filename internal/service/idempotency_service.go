@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// idempotencyProcessingTimeout 处理中的记录超过该时长未刷新视为首次请求已中断，允许重新执行
+// 首次请求处理期间由 KeepAlive 每隔三分之一刷新一次，批量、导入等耗时较长的请求不会被误判
+const idempotencyProcessingTimeout = time.Minute
+
+// IdempotencyService 幂等键存储
+type IdempotencyService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewIdempotencyService(db *gorm.DB, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{db: db, ttl: ttl}
+}
+
+func (s *IdempotencyService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// Begin 登记一次带幂等键的请求
+// 返回的记录 Completed 为 false 表示本次请求需要执行；为 true 表示应回放已保存的响应。
+// 同一幂等键的首次请求仍在处理时返回 ErrIdempotencyInProgress，请求内容不一致时返回 ErrIdempotencyKeyMismatch
+func (s *IdempotencyService) Begin(ctx context.Context, key, route, caller, requestHash string) (*model.IdempotencyRecord, error) {
+	// 最多重试一次：已有记录过期或中断被清理后重新登记
+	for attempt := 0; attempt < 2; attempt++ {
+		record := &model.IdempotencyRecord{
+			Key:         key,
+			Route:       route,
+			Caller:      caller,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(s.ttl),
+		}
+		
+		result := s.conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return record, nil
+		}
+		
+		var existing model.IdempotencyRecord
+		err := s.conn(ctx).Clauses(dbresolver.Write).
+			Where("idempotency_key = ? AND route = ? AND caller = ?", key, route, caller).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		
+		abandoned := !existing.Completed && time.Since(existing.UpdatedAt) > idempotencyProcessingTimeout
+		if existing.ExpiresAt.Before(time.Now()) || abandoned {
+			if err := s.conn(ctx).Delete(&existing).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		
+		if existing.RequestHash != requestHash {
+			return nil, ErrIdempotencyKeyMismatch
+		}
+		if !existing.Completed {
+			return nil, ErrIdempotencyInProgress
+		}
+		return &existing, nil
+	}
+	
+	return nil, ErrIdempotencyInProgress
+}
+
+// KeepAlive 在首次请求处理期间定期刷新记录的更新时间，返回的函数停止刷新，只能调用一次
+func (s *IdempotencyService) KeepAlive(record *model.IdempotencyRecord) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(idempotencyProcessingTimeout / 3)
+		defer ticker.Stop()
+		
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := s.conn(context.Background()).Model(&model.IdempotencyRecord{}).
+					Where("id = ? AND completed = ?", record.ID, false).
+					Update("updated_at", time.Now()).Error
+				if err != nil {
+					log.Printf("Failed to refresh idempotency key: %v", err)
+				}
+			}
+		}
+	}()
+	
+	return func() {
+		close(done)
+	}
+}
+
+// Complete 保存首次请求的响应
+func (s *IdempotencyService) Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, headers, body string) error {
+	return s.conn(ctx).Model(record).Updates(map[string]interface{}{
+		"completed":   true,
+		"status_code": statusCode,
+		"headers":     headers,
+		"body":        body,
+	}).Error
+}
+
+// Release 删除未完成的记录，使客户端可以用同一幂等键重试
+func (s *IdempotencyService) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	return s.conn(ctx).Delete(record).Error
+}
+
+// PurgeExpired 删除已过期的幂等记录
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	result := s.conn(ctx).Where("expires_at < ?", time.Now()).Delete(&model.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// Run 按 interval 定期清理过期记录，ctx 取消时退出；interval 不大于 0 时不启动
+func (s *IdempotencyService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("Failed to purge idempotency records: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d expired idempotency records", purged)
+			}
+		}
+	}
+}
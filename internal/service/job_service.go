@@ -0,0 +1,629 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// jobClaimBatch 每次查询的候选任务数，逐个尝试认领
+	jobClaimBatch = 10
+	// jobProgressInterval 进度最多每隔多久写一次数据库
+	jobProgressInterval = time.Second
+	// jobPurgeInterval 清理过期任务的间隔
+	jobPurgeInterval = time.Hour
+	// jobPurgeBatch 每轮清理的任务数
+	jobPurgeBatch = 500
+	// jobCancelGrace 停机超时取消任务后，等待任务响应取消的时长
+	jobCancelGrace = 5 * time.Second
+)
+
+// JobHandler 执行一种任务，返回值以 JSON 保存为任务结果
+// 返回 PermanentJobError 包装的错误时不再重试；ctx 在停机或失去租约时取消
+type JobHandler func(ctx context.Context, run *JobRun) (interface{}, error)
+
+// JobOptions 入队选项
+type JobOptions struct {
+	RunAt       time.Time // 计划执行时间，零值表示立即执行
+	MaxAttempts int       // 最多执行次数，0 使用 JOB_MAX_ATTEMPTS
+}
+
+// permanentJobError 不可重试的任务错误
+type permanentJobError struct {
+	err error
+}
+
+func (e *permanentJobError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentJobError) Unwrap() error {
+	return e.err
+}
+
+// PermanentJobError 把错误标记为不可重试，如任务参数错误
+func PermanentJobError(err error) error {
+	return &permanentJobError{err: err}
+}
+
+// JobRun 一次任务执行，供 JobHandler 读取参数和汇报进度
+type JobRun struct {
+	Job *model.Job
+	
+	service      *JobService
+	lastProgress time.Time
+}
+
+// Bind 把任务参数解析到 v
+func (r *JobRun) Bind(v interface{}) error {
+	if err := json.Unmarshal([]byte(r.Job.Payload), v); err != nil {
+		return PermanentJobError(fmt.Errorf("任务参数无效: %w", err))
+	}
+	return nil
+}
+
+// Progress 汇报已处理数和总数（0 表示未知），partial 非 nil 时同时保存阶段性结果
+// 最多每秒写一次数据库，处理完最后一项时总会写入
+func (r *JobRun) Progress(ctx context.Context, processed, total int, partial interface{}) error {
+	r.Job.Processed = processed
+	r.Job.Total = total
+	if (total == 0 || processed < total) && time.Since(r.lastProgress) < jobProgressInterval {
+		return nil
+	}
+	r.lastProgress = time.Now()
+	
+	updates := map[string]interface{}{
+		"processed": processed,
+		"total":     total,
+	}
+	if partial != nil {
+		data, err := json.Marshal(partial)
+		if err != nil {
+			return err
+		}
+		updates["result"] = string(data)
+	}
+	return r.service.conn(ctx).Model(&model.Job{}).
+		Where("id = ? AND locked_by = ?", r.Job.ID, r.service.workerID).
+		Updates(updates).Error
+}
+
+// JobService 基于数据库的任务队列和 worker 池，MySQL 和 SQLite 均可使用
+// worker 以条件更新认领任务并持有租约，多实例部署时同一任务只会被一个 worker 执行
+type JobService struct {
+	db           *gorm.DB
+	workerID     string
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	retention    time.Duration
+	
+	handlers map[string]JobHandler
+	cleanups map[string]func(job *model.Job)
+	
+	wake       chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func NewJobService(db *gorm.DB, cfg *config.Config) *JobService {
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	maxAttempts := cfg.JobMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &JobService{
+		db:           db,
+		workerID:     newWorkerID(),
+		workers:      cfg.JobWorkers,
+		pollInterval: cfg.JobPollInterval,
+		lease:        cfg.JobLease,
+		maxAttempts:  maxAttempts,
+		backoffBase:  cfg.JobBackoffBase,
+		backoffMax:   cfg.JobBackoffMax,
+		retention:    cfg.JobRetention,
+		handlers:     make(map[string]JobHandler),
+		cleanups:     make(map[string]func(job *model.Job)),
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		jobCtx:       jobCtx,
+		cancelJobs:   cancelJobs,
+	}
+}
+
+func (s *JobService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// newWorkerID 生成本进程的 worker 标识：主机名-进程号-随机数
+func newWorkerID() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+// Register 注册任务类型的执行函数，需在 Start 之前调用
+func (s *JobService) Register(jobType string, handler JobHandler) {
+	s.handlers[jobType] = handler
+}
+
+// OnPurge 注册任务记录被清理前的回调，用于删除任务产生的文件
+func (s *JobService) OnPurge(jobType string, fn func(job *model.Job)) {
+	s.cleanups[jobType] = fn
+}
+
+// Enqueue 创建任务，调用者、请求 ID 取自 ctx 中的审计信息，执行时沿用
+// 在事务中调用时任务随事务提交
+func (s *JobService) Enqueue(ctx context.Context, jobType string, payload interface{}, opts *JobOptions) (*model.Job, error) {
+	if _, ok := s.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("未知的任务类型: %s", jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	meta := auditMetaFrom(ctx)
+	job := &model.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      model.JobStatusPending,
+		RunAt:       time.Now(),
+		MaxAttempts: s.maxAttempts,
+		Actor:       meta.Actor,
+		RequestID:   meta.RequestID,
+		IP:          meta.IP,
+	}
+	if opts != nil {
+		if !opts.RunAt.IsZero() {
+			job.RunAt = opts.RunAt
+		}
+		if opts.MaxAttempts > 0 {
+			job.MaxAttempts = opts.MaxAttempts
+		}
+	}
+	if err := s.conn(ctx).Create(job).Error; err != nil {
+		return nil, err
+	}
+	
+	// 唤醒空闲的 worker，不必等到下次轮询
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetJobByID 获取任务
+func (s *JobService) GetJobByID(ctx context.Context, id uint) (*model.Job, error) {
+	var job model.Job
+	if err := s.conn(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobs 分页查询任务，最新的在前
+func (s *JobService) GetJobs(ctx context.Context, filter *model.JobFilter, page, pageSize int) ([]*model.Job, int64, error) {
+	var jobs []*model.Job
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.Job{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if len(filter.ExcludeTypes) > 0 {
+		query = query.Where("type NOT IN ?", filter.ExcludeTypes)
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return jobs, total, nil
+}
+
+// CancelJob 取消尚未开始执行的任务
+func (s *JobService) CancelJob(ctx context.Context, id uint) (*model.Job, error) {
+	now := time.Now()
+	result := s.conn(ctx).Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusPending).
+		Updates(map[string]interface{}{
+			"status":      model.JobStatusCanceled,
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	
+	job, err := s.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobNotCancelable
+	}
+	return job, nil
+}
+
+// RetryJob 把失败或已取消的任务重新放回队列，尝试次数清零
+func (s *JobService) RetryJob(ctx context.Context, id uint) (*model.Job, error) {
+	result := s.conn(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, []string{model.JobStatusFailed, model.JobStatusCanceled}).
+		Updates(map[string]interface{}{
+			"status":      model.JobStatusPending,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"error":       "",
+			"finished_at": nil,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	
+	job, err := s.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobNotRetryable
+	}
+	
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Start 启动 worker 池和维护协程；JOB_WORKERS 不大于 0 时本实例只入队不执行
+func (s *JobService) Start() {
+	if s.workers <= 0 {
+		return
+	}
+	
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	s.wg.Add(1)
+	go s.maintain()
+}
+
+// Shutdown 停止领取新任务并等待执行中的任务结束
+// ctx 到期时取消仍在执行的任务，它们被放回队列，由下次启动后或其他实例继续执行
+func (s *JobService) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		s.cancelJobs()
+		return nil
+	case <-ctx.Done():
+		s.cancelJobs()
+		select {
+		case <-done:
+		case <-time.After(jobCancelGrace):
+		}
+		return ctx.Err()
+	}
+}
+
+// work 循环认领并执行任务，队列为空时等待轮询间隔或新任务入队
+func (s *JobService) work() {
+	defer s.wg.Done()
+	
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		
+		job, err := s.claim(context.Background())
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job != nil {
+			s.execute(job)
+			continue
+		}
+		
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// claim 认领一个到期的任务：以状态和尝试次数为条件更新，更新不到行说明已被其他 worker 认领
+func (s *JobService) claim(ctx context.Context) (*model.Job, error) {
+	now := time.Now()
+	var due []*model.Job
+	if err := s.conn(ctx).Clauses(dbresolver.Write).
+		Where("status = ? AND run_at <= ?", model.JobStatusPending, now).
+		Order("run_at, id").Limit(jobClaimBatch).Find(&due).Error; err != nil {
+		return nil, err
+	}
+	
+	lockedUntil := now.Add(s.lease)
+	for _, job := range due {
+		result := s.conn(ctx).Model(&model.Job{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, model.JobStatusPending, job.Attempts).
+			Updates(map[string]interface{}{
+				"status":       model.JobStatusRunning,
+				"attempts":     job.Attempts + 1,
+				"locked_by":    s.workerID,
+				"locked_until": lockedUntil,
+				"started_at":   gorm.Expr("COALESCE(started_at, ?)", now),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = model.JobStatusRunning
+			job.Attempts++
+			job.LockedBy = s.workerID
+			job.LockedUntil = &lockedUntil
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// execute 执行任务并记录结果，执行期间定期续租
+func (s *JobService) execute(job *model.Job) {
+	ctx, cancel := context.WithCancel(s.jobCtx)
+	defer cancel()
+	ctx = WithAuditMeta(ctx, model.AuditMeta{Actor: job.Actor, RequestID: job.RequestID, IP: job.IP})
+	
+	done := make(chan struct{})
+	go s.renew(job, cancel, done)
+	
+	result, err := s.run(ctx, job)
+	close(done)
+	
+	if err := s.finish(context.Background(), job, result, err); err != nil {
+		log.Printf("Failed to record job %d result: %v", job.ID, err)
+	}
+}
+
+// run 调用任务类型的执行函数，panic 视为不可重试的错误
+func (s *JobService) run(ctx context.Context, job *model.Job) (result interface{}, err error) {
+	handler, ok := s.handlers[job.Type]
+	if !ok {
+		return nil, PermanentJobError(fmt.Errorf("未知的任务类型: %s", job.Type))
+	}
+	
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %d (%s) panicked: %v", job.ID, job.Type, r)
+			err = PermanentJobError(fmt.Errorf("任务执行异常: %v", r))
+		}
+	}()
+	return handler(ctx, &JobRun{Job: job, service: s})
+}
+
+// renew 每隔租约的三分之一续租一次；租约已被其他 worker 接管时取消任务
+func (s *JobService) renew(job *model.Job, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(s.lease / 3)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			result := s.conn(context.Background()).Model(&model.Job{}).
+				Where("id = ? AND locked_by = ? AND status = ?", job.ID, s.workerID, model.JobStatusRunning).
+				Update("locked_until", time.Now().Add(s.lease))
+			if result.Error != nil {
+				log.Printf("Failed to renew job %d lease: %v", job.ID, result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				log.Printf("Job %d lease lost, canceling", job.ID)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// finish 记录执行结果：成功、按退避时间重试或失败；因停机中断的任务放回队列且不计入尝试次数
+func (s *JobService) finish(ctx context.Context, job *model.Job, result interface{}, runErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by":    "",
+		"locked_until": nil,
+	}
+	
+	var permanent *permanentJobError
+	switch {
+	case runErr == nil:
+		updates["status"] = model.JobStatusSucceeded
+		updates["finished_at"] = now
+		updates["error"] = ""
+		if result != nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			updates["result"] = string(data)
+		}
+	case s.jobCtx.Err() != nil:
+		updates["status"] = model.JobStatusPending
+		updates["attempts"] = job.Attempts - 1
+		updates["run_at"] = now
+	case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = model.JobStatusFailed
+		updates["finished_at"] = now
+		updates["error"] = truncate(runErr.Error(), 1000)
+	default:
+		updates["status"] = model.JobStatusPending
+		updates["run_at"] = now.Add(backoffDelay(s.backoffBase, s.backoffMax, job.Attempts))
+		updates["error"] = truncate(runErr.Error(), 1000)
+	}
+	
+	return s.conn(ctx).Model(&model.Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, s.workerID).
+		Updates(updates).Error
+}
+
+// maintain 定期接管租约过期的任务并清理过期任务
+func (s *JobService) maintain() {
+	defer s.wg.Done()
+	
+	ticker := time.NewTicker(s.lease / 2)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+	
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.ReapExpired(context.Background()); err != nil {
+				log.Printf("Failed to reap expired jobs: %v", err)
+			}
+			if time.Since(lastPurge) >= jobPurgeInterval {
+				lastPurge = time.Now()
+				if _, err := s.PurgeExpired(context.Background()); err != nil {
+					log.Printf("Failed to purge jobs: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// ReapExpired 接管租约已过期的任务（执行它的进程崩溃或失联）：还有尝试次数的放回队列，否则标记为失败
+func (s *JobService) ReapExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	released := map[string]interface{}{
+		"locked_by":    "",
+		"locked_until": nil,
+	}
+	
+	failed := s.conn(ctx).Model(&model.Job{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", model.JobStatusRunning, now).
+		Updates(merge(released, map[string]interface{}{
+			"status":      model.JobStatusFailed,
+			"error":       "执行任务的进程已退出",
+			"finished_at": now,
+		}))
+	if failed.Error != nil {
+		return 0, failed.Error
+	}
+	
+	requeued := s.conn(ctx).Model(&model.Job{}).
+		Where("status = ? AND locked_until < ?", model.JobStatusRunning, now).
+		Updates(merge(released, map[string]interface{}{
+			"status": model.JobStatusPending,
+			"error":  "执行任务的进程已退出，已重新排队",
+			"run_at": now,
+		}))
+	if requeued.Error != nil {
+		return failed.RowsAffected, requeued.Error
+	}
+	return failed.RowsAffected + requeued.RowsAffected, nil
+}
+
+// PurgeExpired 删除结束超过保留期的任务，删除前调用该类任务注册的清理函数，返回删除的数量
+func (s *JobService) PurgeExpired(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.retention)
+	finished := []string{model.JobStatusSucceeded, model.JobStatusFailed, model.JobStatusCanceled}
+	
+	purged := 0
+	for {
+		var jobs []*model.Job
+		if err := s.conn(ctx).Select("id", "type", "result").
+			Where("status IN ? AND finished_at < ?", finished, before).
+			Limit(jobPurgeBatch).Find(&jobs).Error; err != nil {
+			return purged, err
+		}
+		if len(jobs) == 0 {
+			return purged, nil
+		}
+		
+		ids := make([]uint, len(jobs))
+		for i, job := range jobs {
+			ids[i] = job.ID
+			if cleanup := s.cleanups[job.Type]; cleanup != nil {
+				cleanup(job)
+			}
+		}
+		if err := s.conn(ctx).Where("id IN ?", ids).Delete(&model.Job{}).Error; err != nil {
+			return purged, err
+		}
+		purged += len(jobs)
+	}
+}
+
+// merge 合并两组更新字段
+func merge(base, extra map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// backoffDelay 第 attempts 次失败后的等待时长：base * 2^(attempts-1)，不超过 max
+func backoffDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
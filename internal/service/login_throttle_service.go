@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginThrottledError 登录失败次数过多，需等待 RetryAfter 后重试
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // 达到失败上限被临时锁定，否则为渐进延迟
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("登录失败次数过多，已临时锁定，请在%d秒后重试", retrySeconds(e.RetryAfter))
+	}
+	return fmt.Sprintf("登录失败次数过多，请在%d秒后重试", retrySeconds(e.RetryAfter))
+}
+
+// Is 使 errors.Is(err, ErrLoginThrottled) 成立
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
+// retrySeconds 向上取整的秒数，至少为 1
+func retrySeconds(d time.Duration) int64 {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// 失败计数的主体：已存在的用户按 ID，不存在的用户名或邮箱按登录名，另外按客户端 IP
+func throttleUserKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func throttleLoginKey(login string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func throttleIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginThrottleService 按账号和 IP 统计登录失败次数
+// 第二次失败起每次尝试前需等待 delayBase * 2^(n-2)，达到上限后锁定 lockout；超过 window 没有新的失败时清零
+type LoginThrottleService struct {
+	db          *gorm.DB
+	maxFailures int
+	ipMax       int
+	lockout     time.Duration
+	delayBase   time.Duration
+	window      time.Duration
+}
+
+func NewLoginThrottleService(db *gorm.DB, cfg *config.Config) *LoginThrottleService {
+	return &LoginThrottleService{
+		db:          db,
+		maxFailures: cfg.LoginMaxFailures,
+		ipMax:       cfg.LoginIPMaxFailures,
+		lockout:     cfg.LoginLockoutDuration,
+		delayBase:   cfg.LoginDelayBase,
+		window:      cfg.LoginFailureWindow,
+	}
+}
+
+func (s *LoginThrottleService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// limit 返回主体的失败上限，IP 可能被多个用户共用，上限更高
+func (s *LoginThrottleService) limit(subject string) int {
+	if strings.HasPrefix(subject, "ip:") {
+		return s.ipMax
+	}
+	return s.maxFailures
+}
+
+// wait 计算主体还需等待的时长
+func (s *LoginThrottleService) wait(row *model.LoginThrottle, now time.Time) (time.Duration, bool) {
+	if row.LockedUntil != nil && now.Before(*row.LockedUntil) {
+		return row.LockedUntil.Sub(now), true
+	}
+	if row.Failures < 2 || now.Sub(row.LastFailureAt) > s.window {
+		return 0, false
+	}
+	
+	delay := s.delayBase << uint(row.Failures-2)
+	if delay <= 0 || delay > s.lockout {
+		delay = s.lockout
+	}
+	if next := row.LastFailureAt.Add(delay); now.Before(next) {
+		return next.Sub(now), false
+	}
+	return 0, false
+}
+
+// Check 任一主体处于延迟或锁定期时返回 *LoginThrottledError
+func (s *LoginThrottleService) Check(ctx context.Context, subjects ...string) error {
+	var rows []*model.LoginThrottle
+	if err := s.conn(ctx).Where("subject IN ?", subjects).Find(&rows).Error; err != nil {
+		return err
+	}
+	
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for _, row := range rows {
+		wait, locked := s.wait(row, now)
+		if wait > 0 && (throttled == nil || wait > throttled.RetryAfter) {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: locked}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+// Fail 为各主体记录一次失败，达到上限时锁定
+func (s *LoginThrottleService) Fail(ctx context.Context, subjects ...string) error {
+	for _, subject := range subjects {
+		if err := s.fail(ctx, subject); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LoginThrottleService) fail(ctx context.Context, subject string) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		row := model.LoginThrottle{Subject: subject}
+		if err := s.conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("subject = ?", subject).First(&row).Error; err != nil {
+			return err
+		}
+		
+		now := time.Now()
+		// 锁定已到期或长时间没有失败时重新计数
+		if (row.LockedUntil != nil && !now.Before(*row.LockedUntil)) || now.Sub(row.LastFailureAt) > s.window {
+			row.Failures = 0
+			row.LockedUntil = nil
+		}
+		
+		row.Failures++
+		row.LastFailureAt = now
+		if limit := s.limit(subject); limit > 0 && row.Failures >= limit {
+			lockedUntil := now.Add(s.lockout)
+			row.LockedUntil = &lockedUntil
+		}
+		
+		return s.conn(ctx).Model(&row).Updates(map[string]interface{}{
+			"failures":        row.Failures,
+			"last_failure_at": row.LastFailureAt,
+			"locked_until":    row.LockedUntil,
+		}).Error
+	})
+}
+
+// Reset 登录成功后清除账号的失败计数；IP 的计数不清除，避免攻击者用自己的账号重置
+func (s *LoginThrottleService) Reset(ctx context.Context, subjects ...string) error {
+	return s.conn(ctx).Where("subject IN ?", subjects).Delete(&model.LoginThrottle{}).Error
+}
+
+// Unlock 管理员解除用户的锁定
+func (s *LoginThrottleService) Unlock(ctx context.Context, userID uint) error {
+	return s.Reset(ctx, throttleUserKey(userID))
+}
+
+// PurgeExpired 删除已过期且不在锁定期的计数
+func (s *LoginThrottleService) PurgeExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	result := s.conn(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-s.window), now).
+		Delete(&model.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
+
+// Run 按 interval 定期清理过期的失败计数，ctx 取消时退出；interval 不大于 0 时不启动
+func (s *LoginThrottleService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpired(ctx); err != nil {
+				log.Printf("Failed to purge login throttles: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"topService/internal/database"
+	"topService/internal/model"
+	"topService/internal/tabular"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const (
+	// importChunkSize 导入任务每次提交的行数
+	importChunkSize = 500
+)
+
+// movieImportColumns 导入文件列名（小写）-> MovieCreateRequest 的 JSON 字段名
+var movieImportColumns = map[string]string{
+	"title": "title", "电影名称": "title", "片名": "title",
+	"cover": "cover", "poster": "cover", "封面": "cover",
+	"genre": "genre", "类型": "genre", "电影类型": "genre",
+	"director": "director", "导演": "director",
+	"m3u8": "m3u8", "videourl": "m3u8", "播放地址": "m3u8",
+	"actors": "actors", "主演": "actors",
+	"release_date": "release_date", "上映日期": "release_date",
+	"duration": "duration", "片长": "duration",
+	"language": "language", "语言": "language",
+	"country": "country", "国家/地区": "country", "国家": "country",
+	"rating": "rating", "评分": "rating",
+	"description": "description", "剧情简介": "description", "简介": "description",
+}
+
+// movieDateLayouts 支持的上映日期格式
+var movieDateLayouts = []string{"2006-01-02", "2006/01/02", "2006.01.02", "2006-01-02 15:04:05", time.RFC3339}
+
+// MovieImportMapping 构造导入列映射，custom（列名 -> 字段名）优先于内置的字段名和中文别名
+func MovieImportMapping(custom map[string]string) tabular.Mapping {
+	return func(column string) (string, bool) {
+		if field, ok := custom[column]; ok {
+			return field, field != ""
+		}
+		field, ok := movieImportColumns[strings.ToLower(column)]
+		return field, ok
+	}
+}
+
+// MovieImportService 电影批量导入，写入由 movie.import 后台任务完成
+type MovieImportService struct {
+	db           *gorm.DB
+	movieService *MovieService
+	jobService   *JobService
+}
+
+func NewMovieImportService(db *gorm.DB, movieService *MovieService, jobService *JobService) *MovieImportService {
+	return &MovieImportService{
+		db:           db,
+		movieService: movieService,
+		jobService:   jobService,
+	}
+}
+
+// movieImportPayload movie.import 任务参数
+type movieImportPayload struct {
+	Rows []*model.MovieImportRow `json:"rows"`
+}
+
+// Prepare 校验导入记录并按 标题+上映日期 去重，返回校验报告和可导入的行
+func (s *MovieImportService) Prepare(ctx context.Context, records []tabular.Record) (*model.MovieImportReport, []*model.MovieImportRow, error) {
+	report := &model.MovieImportReport{
+		Total:      len(records),
+		Duplicates: []model.ImportDuplicate{},
+		Errors:     []model.ImportRowError{},
+	}
+	
+	var candidates []*model.MovieImportRow
+	for _, record := range records {
+		req, errs := toMovieCreateRequest(record.Fields)
+		if len(errs) == 0 {
+			if err := binding.Validator.ValidateStruct(req); err != nil {
+				errs = strings.Split(err.Error(), "\n")
+			}
+		}
+		if len(errs) > 0 {
+			report.Invalid++
+			report.Errors = append(report.Errors, model.ImportRowError{Line: record.Line, Errors: errs})
+			continue
+		}
+		candidates = append(candidates, &model.MovieImportRow{Line: record.Line, Request: req})
+	}
+	
+	existing, err := s.existingMovieKeys(ctx, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	seen := make(map[string]bool, len(candidates))
+	rows := make([]*model.MovieImportRow, 0, len(candidates))
+	for _, row := range candidates {
+		key := movieDedupKey(row.Request.Title, row.Request.ReleaseDate)
+		reason := ""
+		switch {
+		case existing[key]:
+			reason = "existing"
+		case seen[key]:
+			reason = "file"
+		}
+		if reason != "" {
+			report.Duplicates = append(report.Duplicates, model.ImportDuplicate{
+				Line:        row.Line,
+				Title:       row.Request.Title,
+				ReleaseDate: formatMovieDate(row.Request.ReleaseDate),
+				Reason:      reason,
+			})
+			continue
+		}
+		seen[key] = true
+		rows = append(rows, row)
+	}
+	
+	report.Valid = len(rows)
+	return report, rows, nil
+}
+
+// existingMovieKeys 查询数据库中与导入行标题相同的电影，返回其去重键
+// 与 movieDedupKey 一致，标题忽略首尾空白和大小写比较
+func (s *MovieImportService) existingMovieKeys(ctx context.Context, rows []*model.MovieImportRow) (map[string]bool, error) {
+	titleSet := make(map[string]bool, len(rows))
+	titles := make([]string, 0, len(rows))
+	for _, row := range rows {
+		title := normalizeMovieTitle(row.Request.Title)
+		if !titleSet[title] {
+			titleSet[title] = true
+			titles = append(titles, title)
+		}
+	}
+	
+	keys := make(map[string]bool)
+	for from := 0; from < len(titles); from += importChunkSize {
+		to := from + importChunkSize
+		if to > len(titles) {
+			to = len(titles)
+		}
+		
+		var movies []*model.Movie
+		if err := database.Conn(ctx, s.db).Select("title", "release_date").
+			Where("LOWER(TRIM(title)) IN ?", titles[from:to]).Find(&movies).Error; err != nil {
+			return nil, err
+		}
+		for _, movie := range movies {
+			keys[movieDedupKey(movie.Title, movie.ReleaseDate)] = true
+		}
+	}
+	
+	return keys, nil
+}
+
+// StartImport 创建导入任务，由后台 worker 分批写入，审计日志沿用发起请求的来源
+func (s *MovieImportService) StartImport(ctx context.Context, rows []*model.MovieImportRow) (*model.MovieImportJob, error) {
+	job, err := s.jobService.Enqueue(ctx, model.JobMovieImport, &movieImportPayload{Rows: rows}, nil)
+	if err != nil {
+		return nil, err
+	}
+	job.Total = len(rows)
+	return toMovieImportJob(job), nil
+}
+
+// GetJob 获取导入任务状态，actor 不为空时只返回该调用者创建的任务，其他任务与不存在一样返回 ErrJobNotFound
+func (s *MovieImportService) GetJob(ctx context.Context, id uint, actor string) (*model.MovieImportJob, error) {
+	job, err := s.jobService.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != model.JobMovieImport || (actor != "" && job.Actor != actor) {
+		return nil, ErrJobNotFound
+	}
+	return toMovieImportJob(job), nil
+}
+
+// toMovieImportJob 把后台任务转换为导入任务视图
+func toMovieImportJob(job *model.Job) *model.MovieImportJob {
+	var result model.MovieImportResult
+	if job.Result != "" {
+		json.Unmarshal([]byte(job.Result), &result)
+	}
+	if result.Errors == nil {
+		result.Errors = []model.ImportRowError{}
+	}
+	
+	return &model.MovieImportJob{
+		ID:         job.ID,
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Created:    result.Created,
+		Failed:     result.Failed,
+		Errors:     result.Errors,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// RunImportJob 执行 movie.import 任务，单行写入失败不影响其他行
+// 重试时从上次记录的进度继续，并跳过上次已写入但未来得及记录进度的行
+func (s *MovieImportService) RunImportJob(ctx context.Context, run *JobRun) (interface{}, error) {
+	var payload movieImportPayload
+	if err := run.Bind(&payload); err != nil {
+		return nil, err
+	}
+	rows := payload.Rows
+	
+	result := &model.MovieImportResult{Errors: []model.ImportRowError{}}
+	if run.Job.Result != "" {
+		if err := json.Unmarshal([]byte(run.Job.Result), result); err != nil {
+			return nil, PermanentJobError(err)
+		}
+	}
+	
+	processed := run.Job.Processed
+	if processed > len(rows) {
+		processed = len(rows)
+	}
+	for from := processed; from < len(rows); from += importChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		
+		to := from + importChunkSize
+		if to > len(rows) {
+			to = len(rows)
+		}
+		chunk, err := s.pendingRows(ctx, rows[from:to], run.Job.Attempts > 1)
+		if err != nil {
+			return nil, err
+		}
+		// 被跳过的行已由上次执行写入
+		result.Created += to - from - len(chunk)
+		
+		reqs := make([]*model.MovieCreateRequest, len(chunk))
+		for i, row := range chunk {
+			reqs[i] = row.Request
+		}
+		
+		_, errs := s.movieService.CreateMovies(ctx, reqs, false)
+		for i, err := range errs {
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, model.ImportRowError{Line: chunk[i].Line, Errors: []string{err.Error()}})
+			} else {
+				result.Created++
+			}
+		}
+		
+		if err := run.Progress(ctx, to, len(rows), result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// pendingRows 重试时过滤掉数据库中已存在的行，首次执行时原样返回
+func (s *MovieImportService) pendingRows(ctx context.Context, rows []*model.MovieImportRow, retry bool) ([]*model.MovieImportRow, error) {
+	if !retry {
+		return rows, nil
+	}
+	
+	existing, err := s.existingMovieKeys(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*model.MovieImportRow, 0, len(rows))
+	for _, row := range rows {
+		if !existing[movieDedupKey(row.Request.Title, row.Request.ReleaseDate)] {
+			pending = append(pending, row)
+		}
+	}
+	return pending, nil
+}
+
+// toMovieCreateRequest 把导入记录转换为创建请求，返回类型转换错误
+func toMovieCreateRequest(fields map[string]string) (*model.MovieCreateRequest, []string) {
+	var errs []string
+	req := &model.MovieCreateRequest{
+		Title:       fields["title"],
+		Cover:       fields["cover"],
+		Genre:       fields["genre"],
+		Director:    fields["director"],
+		M3u8:        fields["m3u8"],
+		Actors:      fields["actors"],
+		Language:    fields["language"],
+		Country:     fields["country"],
+		Description: fields["description"],
+	}
+	
+	if value := fields["release_date"]; value != "" {
+		if date, ok := parseMovieDate(value); ok {
+			req.ReleaseDate = &date
+		} else {
+			errs = append(errs, "release_date: 无法识别的日期 "+value)
+		}
+	}
+	if value := fields["duration"]; value != "" {
+		if duration, err := strconv.ParseFloat(value, 64); err == nil {
+			req.Duration = int(duration)
+		} else {
+			errs = append(errs, "duration: 不是有效的数字 "+value)
+		}
+	}
+	if value := fields["rating"]; value != "" {
+		if rating, err := strconv.ParseFloat(value, 32); err == nil {
+			req.Rating = float32(rating)
+		} else {
+			errs = append(errs, "rating: 不是有效的数字 "+value)
+		}
+	}
+	
+	return req, errs
+}
+
+func parseMovieDate(value string) (time.Time, bool) {
+	for _, layout := range movieDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func formatMovieDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// movieDedupKey 电影去重键：标题（忽略大小写和首尾空白）+ 上映日期
+func movieDedupKey(title string, releaseDate *time.Time) string {
+	return normalizeMovieTitle(title) + "|" + formatMovieDate(releaseDate)
+}
+
+// normalizeMovieTitle 去重时比较的标题：去掉首尾空白并转为小写
+func normalizeMovieTitle(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// saveMovieRevision 记录电影当前版本的内容快照，该版本已有快照时忽略
+// attributed 为 false 时不记录操作者，用于补记更新前的版本（其操作者未知）
+func saveMovieRevision(ctx context.Context, db *gorm.DB, movie *model.Movie, attributed bool) error {
+	snapshot, err := json.Marshal(movie.ToUpdateRequest())
+	if err != nil {
+		return err
+	}
+	
+	revision := &model.MovieRevision{
+		MovieID:  movie.ID,
+		Version:  movie.Version,
+		Snapshot: string(snapshot),
+	}
+	if attributed {
+		meta := auditMetaFrom(ctx)
+		revision.Actor = meta.Actor
+		revision.RequestID = meta.RequestID
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(revision).Error
+}
+
+// GetMovieRevisions 分页获取电影的历史版本，最新的在前；已软删除的电影也可查看
+func (s *MovieService) GetMovieRevisions(ctx context.Context, id uint, page, pageSize int) ([]*model.MovieRevision, int64, error) {
+	var count int64
+	if err := s.conn(ctx).Unscoped().Model(&model.Movie{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if count == 0 {
+		return nil, 0, errors.New("电影不存在")
+	}
+	
+	var revisions []*model.MovieRevision
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.MovieRevision{}).Where("movie_id = ?", id)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("version DESC").Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return revisions, total, nil
+}
+
+// GetMovieRevision 获取电影的指定版本
+func (s *MovieService) GetMovieRevision(ctx context.Context, id, version uint) (*model.MovieRevision, error) {
+	var revision model.MovieRevision
+	if err := s.conn(ctx).Where("movie_id = ? AND version = ?", id, version).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	
+	return &revision, nil
+}
+
+// DiffMovieRevisions 比较电影的两个版本，返回从 from 到 to 有变化的字段
+func (s *MovieService) DiffMovieRevisions(ctx context.Context, id, from, to uint) (*model.MovieRevisionDiff, error) {
+	snapshots := make([]map[string]interface{}, 2)
+	for i, version := range []uint{from, to} {
+		revision, err := s.GetMovieRevision(ctx, id, version)
+		if err != nil {
+			return nil, err
+		}
+		content, err := revision.Content()
+		if err != nil {
+			return nil, err
+		}
+		if snapshots[i], err = auditSnapshot(content); err != nil {
+			return nil, err
+		}
+	}
+	
+	return &model.MovieRevisionDiff{
+		MovieID: id,
+		From:    from,
+		To:      to,
+		Changes: auditDiff(snapshots[0], snapshots[1]),
+	}, nil
+}
+
+// RevertMovie 把电影恢复为历史版本 revision 的内容，作为新版本保存
+// version 为客户端期望的当前版本号，为 0 时不做校验
+func (s *MovieService) RevertMovie(ctx context.Context, id, revision, version uint) (*model.Movie, error) {
+	target, err := s.GetMovieRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	content, err := target.Content()
+	if err != nil {
+		return nil, err
+	}
+	
+	return s.PatchMovie(ctx, id, version, func(req *model.MovieUpdateRequest) error {
+		*req = *content
+		return nil
+	})
+}
@@ -1,23 +1,63 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"time"
+	"topService/internal/cache"
+	"topService/internal/config"
+	"topService/internal/database"
 	"topService/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// movieStatsID 电影统计快照的主键，表中只有这一行
+	movieStatsID = 1
+	// movieEventRetry 订阅电影事件失败后重试的间隔
+	movieEventRetry = 5 * time.Second
+)
+
+// 电影查询缓存的命名空间，路由上的响应缓存使用相同的命名空间，随查询缓存一起失效
+const (
+	MovieListCacheNamespace  = "movies"
+	MovieStatsCacheNamespace = "movie_stats"
 )
 
 type MovieService struct {
 	db *gorm.DB
+	
+	// 首页查询的读穿缓存：列表在电影变化后失效，统计在重新计算后失效
+	lists    *queryCache
+	stats    *queryCache
+	listTTL  time.Duration
+	statsTTL time.Duration
 }
 
-func NewMovieService(db *gorm.DB) *MovieService {
-	return &MovieService{db: db}
+// NewMovieService 创建电影服务，c 为 nil 时不缓存查询结果
+func NewMovieService(db *gorm.DB, c cache.Cache, cfg *config.Config) *MovieService {
+	return &MovieService{
+		db:       db,
+		lists:    newQueryCache(c, MovieListCacheNamespace),
+		stats:    newQueryCache(c, MovieStatsCacheNamespace),
+		listTTL:  cfg.MovieListCacheTTL,
+		statsTTL: cfg.MovieStatsCacheTTL,
+	}
 }
 
-// CreateMovie 创建电影
-func (s *MovieService) CreateMovie(req *model.MovieCreateRequest) (*model.Movie, error) {
-	movie := &model.Movie{
+// conn 返回当前请求使用的数据库连接，处于事务中时返回事务连接
+func (s *MovieService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// newMovie 根据创建请求构造电影
+func newMovie(req *model.MovieCreateRequest) *model.Movie {
+	return &model.Movie{
 		Title:       req.Title,
 		Cover:       req.Cover,
 		Genre:       req.Genre,
@@ -30,9 +70,21 @@ func (s *MovieService) CreateMovie(req *model.MovieCreateRequest) (*model.Movie,
 		Country:     req.Country,
 		Rating:      req.Rating,
 		Description: req.Description,
+		Version:     1,
 	}
+}
+
+// CreateMovie 创建电影
+func (s *MovieService) CreateMovie(ctx context.Context, req *model.MovieCreateRequest) (*model.Movie, error) {
+	movie := newMovie(req)
 	
-	if err := s.db.Create(movie).Error; err != nil {
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Create(movie).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionCreate, model.AuditEntityMovie, movie.ID, nil, movie.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -40,9 +92,9 @@ func (s *MovieService) CreateMovie(req *model.MovieCreateRequest) (*model.Movie,
 }
 
 // GetMovieByID 根据ID获取电影
-func (s *MovieService) GetMovieByID(id uint) (*model.Movie, error) {
+func (s *MovieService) GetMovieByID(ctx context.Context, id uint) (*model.Movie, error) {
 	var movie model.Movie
-	if err := s.db.First(&movie, id).Error; err != nil {
+	if err := s.conn(ctx).First(&movie, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("电影不存在")
 		}
@@ -52,12 +104,12 @@ func (s *MovieService) GetMovieByID(id uint) (*model.Movie, error) {
 	return &movie, nil
 }
 
-// GetMovies 获取电影列表
-func (s *MovieService) GetMovies(page, pageSize int, keyword, genre string) ([]*model.Movie, int64, error) {
-	var movies []*model.Movie
-	var total int64
-	
-	query := s.db.Model(&model.Movie{})
+// listQuery 构造电影列表查询条件，列表与导出共用
+func (s *MovieService) listQuery(ctx context.Context, keyword, genre string, includeDeleted bool) *gorm.DB {
+	query := s.conn(ctx).Model(&model.Movie{})
+	if includeDeleted {
+		query = query.Unscoped()
+	}
 	
 	// 搜索条件
 	if keyword != "" {
@@ -69,6 +121,16 @@ func (s *MovieService) GetMovies(page, pageSize int, keyword, genre string) ([]*
 		query = query.Where("genre = ?", genre)
 	}
 	
+	return query
+}
+
+// GetMovies 获取电影列表，includeDeleted 为 true 时包含已软删除的电影
+func (s *MovieService) GetMovies(ctx context.Context, page, pageSize int, keyword, genre string, includeDeleted bool) ([]*model.Movie, int64, error) {
+	var movies []*model.Movie
+	var total int64
+	
+	query := s.listQuery(ctx, keyword, genre, includeDeleted)
+	
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -83,55 +145,66 @@ func (s *MovieService) GetMovies(page, pageSize int, keyword, genre string) ([]*
 	return movies, total, nil
 }
 
-// UpdateMovie 更新电影
-func (s *MovieService) UpdateMovie(id uint, req *model.MovieUpdateRequest) (*model.Movie, error) {
+// UpdateMovie 全量更新电影（PUT）
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *MovieService) UpdateMovie(ctx context.Context, id, version uint, req *model.MovieUpdateRequest) (*model.Movie, error) {
+	return s.PatchMovie(ctx, id, version, func(current *model.MovieUpdateRequest) error {
+		*current = *req
+		return nil
+	})
+}
+
+// PatchMovie 局部更新电影（PATCH）
+// patch 在行锁内基于当前数据修改全量更新请求，返回错误时整个更新回滚
+func (s *MovieService) PatchMovie(ctx context.Context, id, version uint, patch func(req *model.MovieUpdateRequest) error) (*model.Movie, error) {
 	var movie model.Movie
-	if err := s.db.First(&movie, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("电影不存在")
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		// 加行锁读取，防止并发更新互相覆盖；加锁查询始终路由到主库
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&movie, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("电影不存在")
+			}
+			return err
 		}
-		return nil, err
-	}
-	
-	// 更新字段
-	if req.Title != "" {
+		if version != 0 && movie.Version != version {
+			return ErrVersionConflict
+		}
+		
+		req := movie.ToUpdateRequest()
+		if err := patch(req); err != nil {
+			return err
+		}
+		before := movie.ToResponse()
+		
+		// 更新前的版本还没有快照时（创建后首次更新或早于版本记录的数据）先补记，保证可以回滚到该版本
+		if err := saveMovieRevision(ctx, s.conn(ctx), &movie, false); err != nil {
+			return err
+		}
+		
+		// 全量替换字段
 		movie.Title = req.Title
-	}
-	if req.Cover != "" {
 		movie.Cover = req.Cover
-	}
-	if req.Genre != "" {
 		movie.Genre = req.Genre
-	}
-	if req.Director != "" {
 		movie.Director = req.Director
-	}
-	if req.M3u8 != "" {
 		movie.M3u8 = req.M3u8
-	}
-	if req.Actors != "" {
 		movie.Actors = req.Actors
-	}
-	if req.ReleaseDate != nil {
 		movie.ReleaseDate = req.ReleaseDate
-	}
-	if req.Duration != nil {
-		movie.Duration = *req.Duration
-	}
-	if req.Language != "" {
+		movie.Duration = req.Duration
 		movie.Language = req.Language
-	}
-	if req.Country != "" {
 		movie.Country = req.Country
-	}
-	if req.Rating != nil {
-		movie.Rating = *req.Rating
-	}
-	if req.Description != "" {
+		movie.Rating = req.Rating
 		movie.Description = req.Description
-	}
-	
-	if err := s.db.Save(&movie).Error; err != nil {
+		movie.Version++
+		
+		if err := s.conn(ctx).Save(&movie).Error; err != nil {
+			return err
+		}
+		if err := saveMovieRevision(ctx, s.conn(ctx), &movie, true); err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityMovie, movie.ID, before, movie.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -139,23 +212,44 @@ func (s *MovieService) UpdateMovie(id uint, req *model.MovieUpdateRequest) (*mod
 }
 
 // DeleteMovie 删除电影
-func (s *MovieService) DeleteMovie(id uint) error {
-	result := s.db.Delete(&model.Movie{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	
-	if result.RowsAffected == 0 {
-		return errors.New("电影不存在")
-	}
-	
-	return nil
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *MovieService) DeleteMovie(ctx context.Context, id, version uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var movie model.Movie
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&movie, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("电影不存在")
+			}
+			return err
+		}
+		if version != 0 && movie.Version != version {
+			return ErrVersionConflict
+		}
+		before := movie.ToResponse()
+		
+		now := time.Now()
+		if err := s.conn(ctx).Model(&movie).Update("delete_at", now).Error; err != nil {
+			return err
+		}
+		movie.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		
+		return recordChange(ctx, s.conn(ctx), model.AuditActionDelete, model.AuditEntityMovie, movie.ID, before, movie.ToResponse())
+	})
 }
 
-// GetMoviesByGenre 根据类型获取电影
-func (s *MovieService) GetMoviesByGenre(genre string, limit int) ([]*model.Movie, error) {
+// GetMoviesByGenre 根据类型获取电影，结果缓存 MOVIE_LIST_CACHE_TTL
+func (s *MovieService) GetMoviesByGenre(ctx context.Context, genre string, limit int) ([]*model.Movie, error) {
 	var movies []*model.Movie
-	query := s.db.Model(&model.Movie{})
+	err := s.lists.get(ctx, fmt.Sprintf("by_genre:%d:%s", limit, genre), s.listTTL, &movies, func(ctx context.Context) (interface{}, error) {
+		return s.queryMoviesByGenre(ctx, genre, limit)
+	})
+	return movies, err
+}
+
+// queryMoviesByGenre 查询某一类型评分最高的电影，genre 为空时不限类型
+func (s *MovieService) queryMoviesByGenre(ctx context.Context, genre string, limit int) ([]*model.Movie, error) {
+	var movies []*model.Movie
+	query := s.conn(ctx).Model(&model.Movie{})
 	
 	if genre != "" {
 		query = query.Where("genre = ?", genre)
@@ -172,10 +266,19 @@ func (s *MovieService) GetMoviesByGenre(genre string, limit int) ([]*model.Movie
 	return movies, nil
 }
 
-// GetTopRatedMovies 获取高评分电影
-func (s *MovieService) GetTopRatedMovies(limit int) ([]*model.Movie, error) {
+// GetTopRatedMovies 获取高评分电影，结果缓存 MOVIE_LIST_CACHE_TTL
+func (s *MovieService) GetTopRatedMovies(ctx context.Context, limit int) ([]*model.Movie, error) {
 	var movies []*model.Movie
-	query := s.db.Model(&model.Movie{}).Where("rating >= ?", 8.0)
+	err := s.lists.get(ctx, fmt.Sprintf("top_rated:%d", limit), s.listTTL, &movies, func(ctx context.Context) (interface{}, error) {
+		return s.queryTopRatedMovies(ctx, limit)
+	})
+	return movies, err
+}
+
+// queryTopRatedMovies 查询评分不低于 8.0 的电影
+func (s *MovieService) queryTopRatedMovies(ctx context.Context, limit int) ([]*model.Movie, error) {
+	var movies []*model.Movie
+	query := s.conn(ctx).Model(&model.Movie{}).Where("rating >= ?", 8.0)
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -188,34 +291,255 @@ func (s *MovieService) GetTopRatedMovies(limit int) ([]*model.Movie, error) {
 	return movies, nil
 }
 
-// GetMovieStats 获取电影统计信息
-func (s *MovieService) GetMovieStats() (map[string]interface{}, error) {
+// GetMovieStats 获取电影统计信息，读取定时任务 movie.stats 计算的快照，结果缓存 MOVIE_STATS_CACHE_TTL
+func (s *MovieService) GetMovieStats(ctx context.Context) (*model.MovieStatsResponse, error) {
+	var stats *model.MovieStatsResponse
+	err := s.stats.get(ctx, "snapshot", s.statsTTL, &stats, func(ctx context.Context) (interface{}, error) {
+		return s.queryMovieStats(ctx)
+	})
+	return stats, err
+}
+
+// queryMovieStats 读取统计快照，尚未计算过时（首次部署）当场计算一次
+func (s *MovieService) queryMovieStats(ctx context.Context) (*model.MovieStatsResponse, error) {
+	var stats model.MovieStats
+	err := s.conn(ctx).First(&stats, movieStatsID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		computed, err := s.computeMovieStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return computed.ToResponse(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats.ToResponse(), nil
+}
+
+// RefreshMovieStats 重新计算电影统计并保存快照，供定时任务 movie.stats 调用
+func (s *MovieService) RefreshMovieStats(ctx context.Context) (*model.MovieStatsResponse, error) {
+	stats, err := s.computeMovieStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conn(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(stats).Error; err != nil {
+		return nil, err
+	}
+	if err := s.stats.invalidate(ctx); err != nil {
+		log.Printf("Failed to invalidate movie stats cache: %v", err)
+	}
+	return stats.ToResponse(), nil
+}
+
+// WatchEvents 订阅电影的领域事件，电影被创建、修改、删除或恢复后使列表缓存失效，ctx 取消或事件流关闭时退出
+// 事件来自共享的发件箱，其他实例上的修改同样会使本实例的缓存失效；失效最多延迟 EVENT_STREAM_POLL_INTERVAL
+func (s *MovieService) WatchEvents(ctx context.Context, broker *EventBroker) {
+	if s.lists.cache == nil {
+		return
+	}
+	
+	for {
+		sub, err := broker.Subscribe(ctx, []string{model.AuditEntityMovie})
+		if errors.Is(err, ErrEventBrokerClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to subscribe movie events: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(movieEventRetry):
+			}
+			continue
+		}
+		
+		// 订阅断开期间可能错过事件，重新订阅后先使缓存失效
+		s.invalidateLists(ctx)
+		if !s.consumeEvents(ctx, sub) {
+			broker.Unsubscribe(sub)
+			return
+		}
+	}
+}
+
+// consumeEvents 每收到一批事件使列表缓存失效一次，订阅被断开时返回 true，ctx 取消时返回 false
+func (s *MovieService) consumeEvents(ctx context.Context, sub *EventSubscription) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case _, ok := <-sub.Events:
+			if !ok {
+				return true
+			}
+			// 批量导入等会连续产生大量事件，合并为一次失效
+			for drained := false; !drained; {
+				select {
+				case _, ok := <-sub.Events:
+					if !ok {
+						s.invalidateLists(ctx)
+						return true
+					}
+				default:
+					drained = true
+				}
+			}
+			s.invalidateLists(ctx)
+		}
+	}
+}
+
+// invalidateLists 使高评分和按类型电影列表的缓存失效
+func (s *MovieService) invalidateLists(ctx context.Context) {
+	if err := s.lists.invalidate(ctx); err != nil {
+		log.Printf("Failed to invalidate movie list cache: %v", err)
+	}
+}
+
+// computeMovieStats 统计电影总数、平均评分和各类型电影数量
+func (s *MovieService) computeMovieStats(ctx context.Context) (*model.MovieStats, error) {
 	var total int64
 	var avgRating float64
 	
 	// 总电影数
-	if err := s.db.Model(&model.Movie{}).Count(&total).Error; err != nil {
+	if err := s.conn(ctx).Model(&model.Movie{}).Count(&total).Error; err != nil {
 		return nil, err
 	}
 	
 	// 平均评分
-	if err := s.db.Model(&model.Movie{}).Select("AVG(rating)").Scan(&avgRating).Error; err != nil {
+	if err := s.conn(ctx).Model(&model.Movie{}).Select("COALESCE(AVG(rating), 0)").Scan(&avgRating).Error; err != nil {
 		return nil, err
 	}
 	
 	// 各类型电影数量
-	var genreStats []struct {
-		Genre string `json:"genre"`
-		Count int64  `json:"count"`
-	}
-	if err := s.db.Model(&model.Movie{}).Select("genre, COUNT(*) as count").
+	var genreStats []model.GenreStat
+	if err := s.conn(ctx).Model(&model.Movie{}).Select("genre, COUNT(*) as count").
 		Where("genre != ''").Group("genre").Scan(&genreStats).Error; err != nil {
 		return nil, err
 	}
+	data, err := json.Marshal(genreStats)
+	if err != nil {
+		return nil, err
+	}
 	
-	return map[string]interface{}{
-		"total":       total,
-		"avg_rating":  avgRating,
-		"genre_stats": genreStats,
+	return &model.MovieStats{
+		ID:         movieStatsID,
+		Total:      total,
+		AvgRating:  avgRating,
+		GenreStats: string(data),
+		ComputedAt: time.Now(),
 	}, nil
-}
\ No newline at end of file
+}
+
+// GetDeletedMovies 获取回收站中的电影
+func (s *MovieService) GetDeletedMovies(ctx context.Context, page, pageSize int) ([]*model.Movie, int64, error) {
+	var movies []*model.Movie
+	var total int64
+	
+	query := s.conn(ctx).Unscoped().Model(&model.Movie{}).Where("delete_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("delete_at DESC").Find(&movies).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return movies, total, nil
+}
+
+// RestoreMovie 从回收站恢复电影
+func (s *MovieService) RestoreMovie(ctx context.Context, id uint) (*model.Movie, error) {
+	var movie model.Movie
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("delete_at IS NOT NULL").First(&movie, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该电影")
+			}
+			return err
+		}
+		
+		before := movie.ToResponse()
+		movie.DeletedAt = gorm.DeletedAt{}
+		movie.Version++
+		if err := s.conn(ctx).Unscoped().Save(&movie).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionRestore, model.AuditEntityMovie, movie.ID, before, movie.ToResponse())
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &movie, nil
+}
+
+// PurgeMovie 彻底删除回收站中的电影，同时删除其历史版本和播放地址检查结果
+func (s *MovieService) PurgeMovie(ctx context.Context, id uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var movie model.Movie
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("delete_at IS NOT NULL").First(&movie, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该电影")
+			}
+			return err
+		}
+		
+		if err := s.conn(ctx).Unscoped().Delete(&movie).Error; err != nil {
+			return err
+		}
+		for _, value := range []interface{}{&model.MovieRevision{}, &model.MovieVideoCheck{}} {
+			if err := s.conn(ctx).Where("movie_id = ?", movie.ID).Delete(value).Error; err != nil {
+				return err
+			}
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionPurge, model.AuditEntityMovie, movie.ID, movie.ToResponse(), nil)
+	})
+}
+
+// CreateMovies 批量创建电影，返回与请求一一对应的错误
+// atomic 为 true 时全部成功才提交，否则跳过失败的条目
+func (s *MovieService) CreateMovies(ctx context.Context, reqs []*model.MovieCreateRequest, atomic bool) ([]*model.Movie, []error) {
+	movies := make([]*model.Movie, len(reqs))
+	for i, req := range reqs {
+		movies[i] = newMovie(req)
+	}
+	
+	errs := insertBatch(ctx, s.db, len(movies), atomic, func(db *gorm.DB, from, to int) error {
+		if err := db.CreateInBatches(movies[from:to], batchSize).Error; err != nil {
+			return err
+		}
+		return recordCreates(ctx, db, model.AuditEntityMovie, to-from, func(i int) (uint, interface{}) {
+			return movies[from+i].ID, movies[from+i].ToResponse()
+		})
+	})
+	
+	return movies, errs
+}
+
+// UpdateMovies 批量全量更新电影，返回与条目一一对应的错误
+func (s *MovieService) UpdateMovies(ctx context.Context, items []*model.MovieBatchUpdateItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		_, err := s.UpdateMovie(ctx, items[i].ID, items[i].Version, &items[i].MovieUpdateRequest)
+		return err
+	})
+}
+
+// DeleteMovies 批量删除电影，返回与条目一一对应的错误
+func (s *MovieService) DeleteMovies(ctx context.Context, items []*model.BatchDeleteItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		return s.DeleteMovie(ctx, items[i].ID, items[i].Version)
+	})
+}
+
+// ExportMovies 按列表筛选条件分批读取全部电影，每批交给 fn 处理，避免一次性加载到内存
+func (s *MovieService) ExportMovies(ctx context.Context, keyword, genre string, includeDeleted bool, fn func(movies []*model.Movie) error) error {
+	var batch []*model.Movie
+	return s.listQuery(ctx, keyword, genre, includeDeleted).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/jwt"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcLoginStateTTL 从跳转 IdP 到回调的最长时间
+	oidcLoginStateTTL = 10 * time.Minute
+	// oidcLoginStateAudience 登录状态令牌的 aud，避免与会话令牌混用
+	oidcLoginStateAudience = "oidc-login-state"
+	// oidcJWKSRefreshInterval 遇到未知 kid 时重新拉取 JWKS 的最小间隔
+	oidcJWKSRefreshInterval = time.Minute
+)
+
+// roleRank 多个用户组映射到不同角色时取权限最高的
+var roleRank = map[string]int{
+	model.RoleUser:   1,
+	model.RoleEditor: 2,
+	model.RoleAdmin:  3,
+}
+
+// usernameInvalidChars 从邮箱生成用户名时去掉的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// oidcProvider OIDC 发现文档中用到的字段
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcLoginState 跳转 IdP 前保存在 Cookie 中的登录状态，由会话密钥签名
+type oidcLoginState struct {
+	jwt.RegisteredClaims
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// oidcIDToken ID Token 中用到的声明
+type oidcIDToken struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     *bool  `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Groups            []string
+}
+
+// OIDCService OIDC 授权码 + PKCE 登录
+type OIDCService struct {
+	db          *gorm.DB
+	cfg         *config.Config
+	stateSecret []byte
+	client      *http.Client
+	
+	mu            sync.Mutex
+	provider      *oidcProvider
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewOIDCService(db *gorm.DB, cfg *config.Config) *OIDCService {
+	return &OIDCService{
+		db:          db,
+		cfg:         cfg,
+		stateSecret: []byte(cfg.SessionSecret),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *OIDCService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// Enabled 是否已配置 OIDC 登录
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.OIDCIssuer != "" && s.cfg.OIDCClientID != "" && s.cfg.OIDCRedirectURL != ""
+}
+
+// randomString 生成 URL 安全的随机串
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// StartLogin 生成跳转 IdP 的授权地址，以及需要放入 Cookie 的登录状态
+func (s *OIDCService) StartLogin(ctx context.Context) (string, string, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	
+	login := &oidcLoginState{}
+	for _, field := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		if *field, err = randomString(32); err != nil {
+			return "", "", err
+		}
+	}
+	now := time.Now()
+	login.Audience = jwt.Audience{oidcLoginStateAudience}
+	login.IssuedAt = now.Unix()
+	login.ExpiresAt = now.Add(oidcLoginStateTTL).Unix()
+	
+	stateToken, err := jwt.SignHS256(login, s.stateSecret)
+	if err != nil {
+		return "", "", err
+	}
+	
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.cfg.OIDCClientID},
+		"redirect_uri":          {s.cfg.OIDCRedirectURL},
+		"scope":                 {strings.Join(s.cfg.OIDCScopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	
+	authURL := provider.AuthorizationEndpoint
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + query.Encode()
+	} else {
+		authURL += "?" + query.Encode()
+	}
+	return authURL, stateToken, nil
+}
+
+// FinishLogin 处理 IdP 回调：校验登录状态，用授权码换取并校验 ID Token，然后创建或关联用户
+func (s *OIDCService) FinishLogin(ctx context.Context, code, state, stateToken string) (*model.User, error) {
+	var login oidcLoginState
+	if err := jwt.VerifyHS256(stateToken, s.stateSecret, &login); err != nil {
+		return nil, ErrInvalidLoginState
+	}
+	if !login.Audience.Contains(oidcLoginStateAudience) || login.Validate(time.Now()) != nil {
+		return nil, ErrInvalidLoginState
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(login.State)) != 1 {
+		return nil, ErrInvalidLoginState
+	}
+	
+	rawIDToken, err := s.exchange(ctx, code, login.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.verifyIDToken(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	
+	return s.provisionUser(ctx, idToken)
+}
+
+// discover 获取并缓存 OIDC 发现文档
+func (s *OIDCService) discover(ctx context.Context) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	
+	var provider oidcProvider
+	if err := s.getJSON(ctx, s.cfg.OIDCIssuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("获取OIDC配置失败: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != s.cfg.OIDCIssuer {
+		return nil, fmt.Errorf("OIDC issuer 不匹配: %s", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("OIDC配置缺少必要的端点")
+	}
+	
+	s.provider = &provider
+	return s.provider, nil
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// exchange 用授权码和 PKCE verifier 换取 ID Token
+func (s *OIDCService) exchange(ctx context.Context, code, verifier string) (string, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.cfg.OIDCRedirectURL},
+		"client_id":     {s.cfg.OIDCClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.cfg.OIDCClientID), url.QueryEscape(s.cfg.OIDCClientSecret))
+	}
+	
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("解析令牌响应失败: %w", err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("换取令牌失败: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return "", fmt.Errorf("换取令牌失败: 状态码 %d", resp.StatusCode)
+	}
+	
+	return token.IDToken, nil
+}
+
+// verifyIDToken 校验 ID Token 的签名、issuer、audience、有效期和 nonce
+func (s *OIDCService) verifyIDToken(ctx context.Context, raw, nonce string) (*oidcIDToken, error) {
+	var claims map[string]json.RawMessage
+	err := jwt.VerifyRS256(raw, func(kid string) (*rsa.PublicKey, error) {
+		return s.publicKey(ctx, kid)
+	}, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+	
+	payload, _ := json.Marshal(claims)
+	var idToken oidcIDToken
+	if err := json.Unmarshal(payload, &idToken); err != nil {
+		return nil, fmt.Errorf("ID Token 格式错误: %w", err)
+	}
+	idToken.Groups = stringList(claims[s.cfg.OIDCGroupsClaim])
+	
+	switch {
+	case strings.TrimSuffix(idToken.Issuer, "/") != s.cfg.OIDCIssuer:
+		return nil, errors.New("ID Token issuer 不匹配")
+	case !idToken.Audience.Contains(s.cfg.OIDCClientID):
+		return nil, errors.New("ID Token audience 不匹配")
+	case len(idToken.Audience) > 1 && idToken.AuthorizedParty != s.cfg.OIDCClientID:
+		return nil, errors.New("ID Token azp 不匹配")
+	case idToken.ExpiresAt == 0 || idToken.Validate(time.Now()) != nil:
+		return nil, errors.New("ID Token 已过期")
+	case subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1:
+		return nil, errors.New("ID Token nonce 不匹配")
+	case idToken.Subject == "":
+		return nil, errors.New("ID Token 缺少 sub")
+	}
+	
+	return &idToken, nil
+}
+
+// stringList 解析字符串或字符串数组形式的声明
+func stringList(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil && single != "" {
+		return []string{single}
+	}
+	return nil
+}
+
+// publicKey 按 kid 返回 IdP 公钥，遇到未知 kid 时重新拉取 JWKS（支持 IdP 轮换密钥）
+func (s *OIDCService) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	provider, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(s.keysFetchedAt) < oidcJWKSRefreshInterval {
+		return nil, jwt.ErrInvalidToken
+	}
+	
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(ctx, provider.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("获取JWKS失败: %w", err)
+	}
+	
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	s.keys = keys
+	s.keysFetchedAt = time.Now()
+	
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, jwt.ErrInvalidToken
+}
+
+// mapRole 根据 IdP 用户组计算角色，未配置映射时返回空串表示保持原角色
+func (s *OIDCService) mapRole(groups []string) string {
+	if len(s.cfg.OIDCRoleMapping) == 0 {
+		return ""
+	}
+	
+	role := model.RoleUser
+	for _, group := range groups {
+		mapped, ok := s.cfg.OIDCRoleMapping[group]
+		if ok && model.ValidRole(mapped) && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// provisionUser 按 issuer + subject 查找已关联的用户；首次登录时按邮箱关联已有用户或创建新用户
+func (s *OIDCService) provisionUser(ctx context.Context, idToken *oidcIDToken) (*model.User, error) {
+	email := strings.ToLower(strings.TrimSpace(idToken.Email))
+	emailVerified := idToken.EmailVerified != nil && *idToken.EmailVerified
+	role := s.mapRole(idToken.Groups)
+	
+	var user model.User
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var identity model.UserIdentity
+		err := s.conn(ctx).Where("issuer = ? AND subject = ?", s.cfg.OIDCIssuer, idToken.Subject).First(&identity).Error
+		switch {
+		case err == nil:
+			err = s.conn(ctx).First(&user, identity.UserID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 关联的用户已被删除，重新按邮箱关联或创建
+				if err := s.conn(ctx).Delete(&identity).Error; err != nil {
+					return err
+				}
+				identity = model.UserIdentity{}
+			} else if err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		
+		if identity.ID == 0 {
+			if email == "" {
+				return errors.New("身份提供方未返回邮箱")
+			}
+			if idToken.EmailVerified != nil && !*idToken.EmailVerified {
+				return ErrEmailNotVerified
+			}
+			
+			err := s.conn(ctx).Where("email = ?", email).First(&user).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if user, err = s.createUser(ctx, email, idToken.PreferredUsername, role, emailVerified); err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			case !emailVerified:
+				// 未声明邮箱已验证时不能关联已有用户，否则不校验邮箱的 IdP 可接管任意本地账号
+				return ErrEmailNotVerified
+			}
+			
+			identity = model.UserIdentity{UserID: user.ID, Issuer: s.cfg.OIDCIssuer, Subject: idToken.Subject}
+		}
+		
+		identity.Email = email
+		if err := s.conn(ctx).Save(&identity).Error; err != nil {
+			return err
+		}
+		
+		if user.Status != 1 {
+			return ErrUserDisabled
+		}
+		before := user.ToResponse()
+		updates := map[string]interface{}{}
+		if role != "" && user.Role != role {
+			user.Role = role
+			updates["role"] = role
+		}
+		// 身份提供方确认过的邮箱视为已验证
+		if user.EmailVerifiedAt == nil && emailVerified && strings.EqualFold(user.Email, email) {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			updates["email_verified_at"] = now
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		user.Version++
+		updates["version"] = user.Version
+		if err := s.conn(ctx).Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse())
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &user, nil
+}
+
+// createUser 为首次登录的用户创建账号，用户名取自 preferred_username 或邮箱前缀，重名时追加序号
+// verified 为 true 时邮箱直接记为已验证
+func (s *OIDCService) createUser(ctx context.Context, email, preferred, role string, verified bool) (model.User, error) {
+	base := usernameInvalidChars.ReplaceAllString(preferred, "")
+	if base == "" {
+		base = usernameInvalidChars.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	
+	if role == "" {
+		role = model.RoleUser
+	}
+	user := model.User{Email: email, Status: 1, Role: role, Version: 1}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	
+	for i := 1; i <= 100; i++ {
+		user.Username = base
+		if i > 1 {
+			user.Username = fmt.Sprintf("%s-%d", base, i)
+		}
+		
+		var count int64
+		if err := s.conn(ctx).Model(&model.User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+			return user, err
+		}
+		if count == 0 {
+			if err := s.conn(ctx).Create(&user).Error; err != nil {
+				return user, err
+			}
+			return user, recordChange(ctx, s.conn(ctx), model.AuditActionCreate, model.AuditEntityUser, user.ID, nil, user.ToResponse())
+		}
+	}
+	
+	return user, errors.New("无法生成可用的用户名")
+}
@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"time"
+	"topService/internal/database"
 	"topService/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductService struct {
@@ -15,18 +19,35 @@ func NewProductService(db *gorm.DB) *ProductService {
 	return &ProductService{db: db}
 }
 
-// CreateProduct 创建产品
-func (s *ProductService) CreateProduct(req *model.ProductCreateRequest) (*model.Product, error) {
-	product := &model.Product{
+// conn 返回当前请求使用的数据库连接，处于事务中时返回事务连接
+func (s *ProductService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// newProduct 根据创建请求构造产品
+func newProduct(req *model.ProductCreateRequest) *model.Product {
+	return &model.Product{
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
 		Stock:       req.Stock,
 		Category:    req.Category,
 		Status:      1,
+		Version:     1,
 	}
+}
+
+// CreateProduct 创建产品
+func (s *ProductService) CreateProduct(ctx context.Context, req *model.ProductCreateRequest) (*model.Product, error) {
+	product := newProduct(req)
 	
-	if err := s.db.Create(product).Error; err != nil {
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Create(product).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionCreate, model.AuditEntityProduct, product.ID, nil, product.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -34,9 +55,9 @@ func (s *ProductService) CreateProduct(req *model.ProductCreateRequest) (*model.
 }
 
 // GetProductByID 根据ID获取产品
-func (s *ProductService) GetProductByID(id uint) (*model.Product, error) {
+func (s *ProductService) GetProductByID(ctx context.Context, id uint) (*model.Product, error) {
 	var product model.Product
-	if err := s.db.First(&product, id).Error; err != nil {
+	if err := s.conn(ctx).First(&product, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("产品不存在")
 		}
@@ -46,12 +67,12 @@ func (s *ProductService) GetProductByID(id uint) (*model.Product, error) {
 	return &product, nil
 }
 
-// GetProducts 获取产品列表
-func (s *ProductService) GetProducts(page, pageSize int, keyword, category string) ([]*model.Product, int64, error) {
-	var products []*model.Product
-	var total int64
-	
-	query := s.db.Model(&model.Product{})
+// listQuery 构造产品列表查询条件，列表与导出共用
+func (s *ProductService) listQuery(ctx context.Context, keyword, category string, includeDeleted bool) *gorm.DB {
+	query := s.conn(ctx).Model(&model.Product{})
+	if includeDeleted {
+		query = query.Unscoped()
+	}
 	
 	// 搜索条件
 	if keyword != "" {
@@ -62,6 +83,16 @@ func (s *ProductService) GetProducts(page, pageSize int, keyword, category strin
 		query = query.Where("category = ?", category)
 	}
 	
+	return query
+}
+
+// GetProducts 获取产品列表，includeDeleted 为 true 时包含已软删除的产品
+func (s *ProductService) GetProducts(ctx context.Context, page, pageSize int, keyword, category string, includeDeleted bool) ([]*model.Product, int64, error) {
+	var products []*model.Product
+	var total int64
+	
+	query := s.listQuery(ctx, keyword, category, includeDeleted)
+	
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -76,53 +107,235 @@ func (s *ProductService) GetProducts(page, pageSize int, keyword, category strin
 	return products, total, nil
 }
 
-// UpdateProduct 更新产品
-func (s *ProductService) UpdateProduct(id uint, req *model.ProductUpdateRequest) (*model.Product, error) {
+// UpdateProduct 全量更新产品（PUT）
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *ProductService) UpdateProduct(ctx context.Context, id, version uint, req *model.ProductUpdateRequest) (*model.Product, error) {
+	return s.PatchProduct(ctx, id, version, func(current *model.ProductUpdateRequest) error {
+		*current = *req
+		return nil
+	})
+}
+
+// PatchProduct 局部更新产品（PATCH）
+// patch 在行锁内基于当前数据修改全量更新请求，返回错误时整个更新回滚
+func (s *ProductService) PatchProduct(ctx context.Context, id, version uint, patch func(req *model.ProductUpdateRequest) error) (*model.Product, error) {
 	var product model.Product
-	if err := s.db.First(&product, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("产品不存在")
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		// 加行锁读取，防止并发更新互相覆盖；加锁查询始终路由到主库
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("产品不存在")
+			}
+			return err
 		}
-		return nil, err
-	}
-	
-	// 更新字段
-	if req.Name != "" {
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+		
+		req := product.ToUpdateRequest()
+		if err := patch(req); err != nil {
+			return err
+		}
+		before := product.ToResponse()
+		
+		// 全量替换字段
 		product.Name = req.Name
-	}
-	if req.Description != "" {
 		product.Description = req.Description
+		product.Price = req.Price
+		product.Stock = req.Stock
+		product.Category = req.Category
+		product.Status = *req.Status
+		product.Version++
+		
+		if err := s.conn(ctx).Save(&product).Error; err != nil {
+			return err
+		}
+		if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityProduct, product.ID, before, product.ToResponse()); err != nil {
+			return err
+		}
+		return recordStockChange(s.conn(ctx), &product, before.Stock)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if req.Price != nil {
-		product.Price = *req.Price
+	
+	return &product, nil
+}
+
+// DeleteProduct 删除产品
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *ProductService) DeleteProduct(ctx context.Context, id, version uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var product model.Product
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("产品不存在")
+			}
+			return err
+		}
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+		before := product.ToResponse()
+		
+		now := time.Now()
+		if err := s.conn(ctx).Model(&product).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		product.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		
+		return recordChange(ctx, s.conn(ctx), model.AuditActionDelete, model.AuditEntityProduct, product.ID, before, product.ToResponse())
+	})
+}
+
+// recordStockChange 库存有变化时额外写入 product.stock_changed 事件，便于下游只关注库存
+func recordStockChange(db *gorm.DB, product *model.Product, before int) error {
+	if product.Stock == before {
+		return nil
 	}
-	if req.Stock != nil {
-		product.Stock = *req.Stock
+	return recordEvent(db, model.EventProductStockChanged, model.AuditEntityProduct, product.ID, product.ToResponse(), map[string]model.FieldChange{
+		"stock": {Before: before, After: product.Stock},
+	})
+}
+
+// DecreaseStock 扣减库存
+// 加行锁校验库存，保证库存不会被扣成负数，可与其他操作组合在同一事务中（如创建订单）
+func (s *ProductService) DecreaseStock(ctx context.Context, id uint, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("扣减数量必须大于0")
 	}
-	if req.Category != "" {
-		product.Category = req.Category
+	
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var product model.Product
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInsufficientStock
+			}
+			return err
+		}
+		if product.Stock < quantity {
+			return ErrInsufficientStock
+		}
+		before := product.ToResponse()
+		
+		product.Stock -= quantity
+		product.Version++
+		if err := s.conn(ctx).Model(&product).Updates(map[string]interface{}{
+			"stock":   product.Stock,
+			"version": product.Version,
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityProduct, product.ID, before, product.ToResponse()); err != nil {
+			return err
+		}
+		return recordStockChange(s.conn(ctx), &product, before.Stock)
+	})
+}
+
+// GetDeletedProducts 获取回收站中的产品
+func (s *ProductService) GetDeletedProducts(ctx context.Context, page, pageSize int) ([]*model.Product, int64, error) {
+	var products []*model.Product
+	var total int64
+	
+	query := s.conn(ctx).Unscoped().Model(&model.Product{}).Where("deleted_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	if req.Status != nil {
-		product.Status = *req.Status
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("deleted_at DESC").Find(&products).Error; err != nil {
+		return nil, 0, err
 	}
 	
-	if err := s.db.Save(&product).Error; err != nil {
+	return products, total, nil
+}
+
+// RestoreProduct 从回收站恢复产品
+func (s *ProductService) RestoreProduct(ctx context.Context, id uint) (*model.Product, error) {
+	var product model.Product
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").First(&product, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该产品")
+			}
+			return err
+		}
+		
+		before := product.ToResponse()
+		product.DeletedAt = gorm.DeletedAt{}
+		product.Version++
+		if err := s.conn(ctx).Unscoped().Save(&product).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionRestore, model.AuditEntityProduct, product.ID, before, product.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
 	return &product, nil
 }
 
-// DeleteProduct 删除产品
-func (s *ProductService) DeleteProduct(id uint) error {
-	result := s.db.Delete(&model.Product{}, id)
-	if result.Error != nil {
-		return result.Error
+// PurgeProduct 彻底删除回收站中的产品
+func (s *ProductService) PurgeProduct(ctx context.Context, id uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var product model.Product
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").First(&product, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该产品")
+			}
+			return err
+		}
+		
+		if err := s.conn(ctx).Unscoped().Delete(&product).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionPurge, model.AuditEntityProduct, product.ID, product.ToResponse(), nil)
+	})
+}
+
+// CreateProducts 批量创建产品，返回与请求一一对应的错误
+// atomic 为 true 时全部成功才提交，否则跳过失败的条目
+func (s *ProductService) CreateProducts(ctx context.Context, reqs []*model.ProductCreateRequest, atomic bool) ([]*model.Product, []error) {
+	products := make([]*model.Product, len(reqs))
+	for i, req := range reqs {
+		products[i] = newProduct(req)
 	}
 	
-	if result.RowsAffected == 0 {
-		return errors.New("产品不存在")
-	}
+	errs := insertBatch(ctx, s.db, len(products), atomic, func(db *gorm.DB, from, to int) error {
+		if err := db.CreateInBatches(products[from:to], batchSize).Error; err != nil {
+			return err
+		}
+		return recordCreates(ctx, db, model.AuditEntityProduct, to-from, func(i int) (uint, interface{}) {
+			return products[from+i].ID, products[from+i].ToResponse()
+		})
+	})
 	
-	return nil
-}
\ No newline at end of file
+	return products, errs
+}
+
+// UpdateProducts 批量全量更新产品，返回与条目一一对应的错误
+func (s *ProductService) UpdateProducts(ctx context.Context, items []*model.ProductBatchUpdateItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		_, err := s.UpdateProduct(ctx, items[i].ID, items[i].Version, &items[i].ProductUpdateRequest)
+		return err
+	})
+}
+
+// DeleteProducts 批量删除产品，返回与条目一一对应的错误
+func (s *ProductService) DeleteProducts(ctx context.Context, items []*model.BatchDeleteItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		return s.DeleteProduct(ctx, items[i].ID, items[i].Version)
+	})
+}
+
+// ExportProducts 按列表筛选条件分批读取全部产品，每批交给 fn 处理，避免一次性加载到内存
+func (s *ProductService) ExportProducts(ctx context.Context, keyword, category string, includeDeleted bool, fn func(products []*model.Product) error) error {
+	var batch []*model.Product
+	return s.listQuery(ctx, keyword, category, includeDeleted).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
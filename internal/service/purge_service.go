@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"time"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// PurgeService 彻底删除超过保留期的软删除数据
+type PurgeService struct {
+	db        *gorm.DB
+	retention time.Duration
+	targets   []purgeTarget
+}
+
+// purgeTarget 支持软删除的表、删除时间列，以及彻底删除单条记录的方法
+// 逐条调用各服务的 Purge 方法，与回收站中手动彻底删除一样写入审计日志和事件，并删除依附的数据
+type purgeTarget struct {
+	name   string
+	value  interface{}
+	column string
+	purge  func(ctx context.Context, id uint) error
+}
+
+func NewPurgeService(db *gorm.DB, retention time.Duration, users *UserService, products *ProductService, movies *MovieService) *PurgeService {
+	return &PurgeService{
+		db:        db,
+		retention: retention,
+		targets: []purgeTarget{
+			{"users", &model.User{}, "deleted_at", users.PurgeUser},
+			{"products", &model.Product{}, "deleted_at", products.PurgeProduct},
+			{"movies", &model.Movie{}, "delete_at", movies.PurgeMovie},
+		},
+	}
+}
+
+// PurgeExpired 彻底删除软删除时间早于保留期的记录，返回各表删除的行数
+// 单条记录删除失败（如期间被恢复）时跳过并继续，最后返回第一个错误
+func (s *PurgeService) PurgeExpired(ctx context.Context) (map[string]int64, error) {
+	before := time.Now().Add(-s.retention)
+	purged := make(map[string]int64, len(s.targets))
+	var firstErr error
+	
+	for _, target := range s.targets {
+		var ids []uint
+		if err := database.Conn(ctx, s.db).Unscoped().Model(target.value).
+			Where(target.column+" < ?", before).
+			Order("id").Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		
+		purged[target.name] = 0
+		for _, id := range ids {
+			if err := target.purge(ctx, id); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			purged[target.name]++
+		}
+	}
+	
+	return purged, firstErr
+}
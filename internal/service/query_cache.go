@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+	"topService/internal/cache"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// queryCacheLoadTimeout 合并后的查询的超时时间，查询不随任何一个请求取消
+const queryCacheLoadTimeout = 30 * time.Second
+
+// detachedContext 保留 ctx 中的值（数据库连接、审计信息等），但不继承其截止时间和取消
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+// queryCache 查询结果的读穿缓存，结果以 JSON 保存
+// 未命中时同一个键的并发查询由 singleflight 合并为一次，避免缓存失效瞬间的大量请求同时打到数据库
+// 键带有命名空间的代号（见 cache.Generation），invalidate 更换代号使命名空间下的旧键全部失效
+// 缓存不可用时记录日志并直接查询数据库
+type queryCache struct {
+	cache     cache.Cache // 为 nil 时不缓存
+	namespace string
+	group     singleflight.Group
+}
+
+func newQueryCache(c cache.Cache, namespace string) *queryCache {
+	return &queryCache{cache: c, namespace: namespace}
+}
+
+// get 把 key 对应的查询结果读取到 dest（指针），未命中时调用 load 查询并缓存 ttl
+// load 的返回值类型须与 dest 指向的类型相同；ttl 不大于 0 时不缓存
+func (q *queryCache) get(ctx context.Context, key string, ttl time.Duration, dest interface{}, load func(ctx context.Context) (interface{}, error)) error {
+	if q.cache == nil || ttl <= 0 {
+		value, err := load(ctx)
+		if err != nil {
+			return err
+		}
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(value))
+		return nil
+	}
+	
+	generation, err := cache.Generation(ctx, q.cache, q.namespace)
+	if err != nil {
+		log.Printf("Failed to read cache generation of %s: %v", q.namespace, err)
+	}
+	key = q.namespace + ":" + generation + ":" + key
+	
+	if generation != "" {
+		data, ok, err := q.cache.Get(ctx, key)
+		if err != nil {
+			log.Printf("Failed to read cache %s: %v", key, err)
+		}
+		if ok {
+			return json.Unmarshal(data, dest)
+		}
+	}
+	
+	// 合并的查询由多个请求共享，不能因第一个请求断开或超时而让其他请求一起失败；各调用方只等待到自己的 ctx 结束
+	results := q.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(detachedContext{ctx}, queryCacheLoadTimeout)
+		defer cancel()
+		
+		value, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if generation != "" {
+			if err := q.cache.Set(loadCtx, key, data, ttl); err != nil {
+				log.Printf("Failed to write cache %s: %v", key, err)
+			}
+		}
+		return data, nil
+	})
+	
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return result.Err
+		}
+		// 各调用方分别解析同一份 JSON，互不共享结果中的指针
+		return json.Unmarshal(result.Val.([]byte), dest)
+	}
+}
+
+// invalidate 使命名空间下已缓存的查询结果全部失效
+func (q *queryCache) invalidate(ctx context.Context) error {
+	if q.cache == nil {
+		return nil
+	}
+	_, err := cache.Rotate(ctx, q.cache, q.namespace)
+	return err
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+	"topService/internal/cache"
+	"topService/internal/config"
+	"topService/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// countingLoad 返回每次调用都递增的查询函数和调用次数
+func countingLoad() (func(ctx context.Context) (interface{}, error), *int32) {
+	var calls int32
+	return func(ctx context.Context) (interface{}, error) {
+		return int(atomic.AddInt32(&calls, 1)), nil
+	}, &calls
+}
+
+func TestQueryCacheReloadsAfterInvalidate(t *testing.T) {
+	ctx := context.Background()
+	q := newQueryCache(cache.NewLRU(100), "test")
+	load, calls := countingLoad()
+	
+	var value int
+	for i := 0; i < 3; i++ {
+		if err := q.get(ctx, "key", time.Minute, &value, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if value != 1 || *calls != 1 {
+		t.Fatalf("value = %d, calls = %d, want cached result of the first load", value, *calls)
+	}
+	
+	if err := q.invalidate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.get(ctx, "key", time.Minute, &value, load); err != nil {
+		t.Fatal(err)
+	}
+	if value != 2 || *calls != 2 {
+		t.Errorf("value = %d, calls = %d, want a reload after invalidate", value, *calls)
+	}
+}
+
+func TestQueryCacheWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	q := newQueryCache(nil, "test")
+	load, calls := countingLoad()
+	
+	var value int
+	for i := 0; i < 2; i++ {
+		if err := q.get(ctx, "key", time.Minute, &value, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if value != 2 || *calls != 2 {
+		t.Errorf("value = %d, calls = %d, want every call to load", value, *calls)
+	}
+}
+
+func TestQueryCacheSharedLoadOutlivesCaller(t *testing.T) {
+	q := newQueryCache(cache.NewLRU(100), "test")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		// 第一个调用方已取消，合并的查询不应随之取消
+		return 1, ctx.Err()
+	}
+	
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		var value int
+		firstErr <- q.get(first, "key", time.Minute, &value, load)
+	}()
+	<-started
+	
+	secondErr := make(chan error, 1)
+	var second int
+	go func() {
+		secondErr <- q.get(context.Background(), "key", time.Minute, &second, load)
+	}()
+	
+	cancel()
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("canceled caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-secondErr; err != nil || second != 1 {
+		t.Errorf("waiting caller got %d, %v, want 1, nil", second, err)
+	}
+}
+
+// newTestMovieService 创建使用临时 SQLite 数据库和 LRU 缓存的电影服务
+func newTestMovieService(t *testing.T) (*MovieService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Movie{}, &model.MovieStats{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{MovieListCacheTTL: time.Minute, MovieStatsCacheTTL: time.Minute}
+	return NewMovieService(db, cache.NewLRU(100), cfg), db
+}
+
+func TestMovieStatsReloadAfterRefresh(t *testing.T) {
+	ctx := context.Background()
+	s, db := newTestMovieService(t)
+	if _, err := s.RefreshMovieStats(ctx); err != nil {
+		t.Fatal(err)
+	}
+	
+	stats, err := s.GetMovieStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 0 {
+		t.Fatalf("Total = %d, want 0", stats.Total)
+	}
+	
+	// 直接写库不经过服务，缓存中仍是旧快照
+	if err := db.Create(&model.Movie{Title: "A", Genre: "Drama", Rating: 9}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = s.GetMovieStats(ctx); stats.Total != 0 {
+		t.Fatalf("Total = %d, want the cached 0", stats.Total)
+	}
+	
+	if _, err := s.RefreshMovieStats(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = s.GetMovieStats(ctx); stats.Total != 1 {
+		t.Errorf("Total = %d, want 1 after refresh", stats.Total)
+	}
+}
+
+func TestMovieEventsInvalidateLists(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestMovieService(t)
+	load, calls := countingLoad()
+	
+	var value int
+	s.lists.get(ctx, "top_rated:10", time.Minute, &value, load)
+	s.lists.get(ctx, "top_rated:10", time.Minute, &value, load)
+	if *calls != 1 {
+		t.Fatalf("calls = %d, want 1 before any event", *calls)
+	}
+	
+	// 一批事件只使缓存失效一次，订阅关闭时 consumeEvents 返回 true
+	events := make(chan *model.OutboxEvent, 3)
+	for i := 0; i < 3; i++ {
+		events <- &model.OutboxEvent{ID: uint(i + 1), ResourceType: model.AuditEntityMovie}
+	}
+	close(events)
+	if !s.consumeEvents(ctx, &EventSubscription{Events: events}) {
+		t.Fatal("consumeEvents should report a closed subscription")
+	}
+	
+	s.lists.get(ctx, "top_rated:10", time.Minute, &value, load)
+	if *calls != 2 || value != 2 {
+		t.Errorf("calls = %d, value = %d, want a reload after movie events", *calls, value)
+	}
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// scheduleOff 表示定时任务不按时间执行，只能手动触发
+	scheduleOff = "off"
+	// taskLease 定时任务的租约时长，执行期间每隔三分之一续租一次
+	taskLease = time.Minute
+	// taskRunPurgeInterval 清理过期执行记录的间隔
+	taskRunPurgeInterval = time.Hour
+)
+
+// ScheduledFunc 定时任务的执行函数，返回值以 JSON 保存为执行结果；ctx 在停机或失去租约时取消
+type ScheduledFunc func(ctx context.Context) (interface{}, error)
+
+// scheduledTask 已注册的定时任务
+type scheduledTask struct {
+	name     string
+	spec     string
+	schedule cron.Schedule // 为 nil 时只能手动触发
+	fn       ScheduledFunc
+}
+
+// Scheduler 按 cron 表达式执行定时任务
+// 调度状态保存在 scheduled_tasks 表中，实例以条件更新抢占任务的租约，多实例部署时每次到期只由一个实例执行
+type Scheduler struct {
+	db           *gorm.DB
+	instance     string
+	pollInterval time.Duration
+	retention    time.Duration
+	
+	tasks map[string]*scheduledTask
+	
+	stop       chan struct{}
+	stopOnce   sync.Once
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func NewScheduler(db *gorm.DB, cfg *config.Config) *Scheduler {
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	return &Scheduler{
+		db:           db,
+		instance:     newWorkerID(),
+		pollInterval: cfg.SchedulerPollInterval,
+		retention:    cfg.TaskRunRetention,
+		tasks:        make(map[string]*scheduledTask),
+		stop:         make(chan struct{}),
+		runCtx:       runCtx,
+		cancelRuns:   cancelRuns,
+	}
+}
+
+func (s *Scheduler) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// Register 注册定时任务，需在 Start 之前调用
+// spec 为标准的五段 cron 表达式或 @hourly、@every 1h 等描述符，为空或 off 时只能手动触发
+func (s *Scheduler) Register(name, spec string, fn ScheduledFunc) error {
+	task := &scheduledTask{name: name, fn: fn}
+	if spec != "" && spec != scheduleOff {
+		schedule, err := cron.ParseStandard(spec)
+		if err != nil {
+			return fmt.Errorf("定时任务 %s 的 cron 表达式无效: %w", name, err)
+		}
+		task.spec = spec
+		task.schedule = schedule
+	}
+	s.tasks[name] = task
+	return nil
+}
+
+// Start 写入各任务的调度状态并启动调度协程；SCHEDULER_POLL_INTERVAL 不大于 0 时本实例不执行到期任务，但仍可手动触发
+func (s *Scheduler) Start(ctx context.Context) error {
+	for _, task := range s.tasks {
+		if err := s.sync(ctx, task); err != nil {
+			return err
+		}
+	}
+	if s.pollInterval <= 0 {
+		return nil
+	}
+	
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// sync 创建任务的调度状态，cron 表达式变化时重新计算下次执行时间
+func (s *Scheduler) sync(ctx context.Context, task *scheduledTask) error {
+	var next *time.Time
+	if task.schedule != nil {
+		at := task.schedule.Next(time.Now())
+		next = &at
+	}
+	
+	row := &model.ScheduledTask{Name: task.name, Schedule: task.spec, NextRunAt: next}
+	if err := s.conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error; err != nil {
+		return err
+	}
+	return s.conn(ctx).Model(&model.ScheduledTask{}).
+		Where("name = ? AND schedule <> ?", task.name, task.spec).
+		Updates(map[string]interface{}{
+			"schedule":    task.spec,
+			"next_run_at": next,
+		}).Error
+}
+
+// Shutdown 停止调度并等待执行中的任务结束，ctx 到期时取消它们
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		s.cancelRuns()
+		return nil
+	case <-ctx.Done():
+		s.cancelRuns()
+		select {
+		case <-done:
+		case <-time.After(jobCancelGrace):
+		}
+		return ctx.Err()
+	}
+}
+
+// loop 每隔轮询间隔检查到期的任务，并定期清理过期的执行记录
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+	
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.tick(context.Background())
+			if time.Since(lastPurge) >= taskRunPurgeInterval {
+				lastPurge = time.Now()
+				if _, err := s.PurgeRuns(context.Background()); err != nil {
+					log.Printf("Failed to purge task runs: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// tick 认领并执行所有到期的任务
+func (s *Scheduler) tick(ctx context.Context) {
+	if _, err := s.reapRuns(ctx); err != nil {
+		log.Printf("Failed to reap task runs: %v", err)
+	}
+	
+	for _, task := range s.tasks {
+		if task.schedule == nil {
+			continue
+		}
+		claimed, err := s.claim(ctx, task, true)
+		if err != nil {
+			log.Printf("Failed to claim task %s: %v", task.name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := s.start(ctx, task, model.TaskTriggerSchedule); err != nil {
+			log.Printf("Failed to start task %s: %v", task.name, err)
+		}
+	}
+}
+
+// claim 抢占任务的租约，due 为 true 时只认领已到期的任务并计算下次执行时间
+// 以租约为条件更新，更新不到行说明任务未到期或正在其他实例上执行
+func (s *Scheduler) claim(ctx context.Context, task *scheduledTask, due bool) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by":    s.instance,
+		"locked_until": now.Add(taskLease),
+	}
+	query := s.conn(ctx).Model(&model.ScheduledTask{}).
+		Where("name = ? AND (locked_until IS NULL OR locked_until < ?)", task.name, now)
+	if due {
+		query = query.Where("next_run_at <= ?", now)
+		updates["next_run_at"] = task.schedule.Next(now)
+	}
+	
+	result := query.Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
+
+// release 释放本实例持有的租约
+func (s *Scheduler) release(ctx context.Context, name string) error {
+	return s.conn(ctx).Model(&model.ScheduledTask{}).
+		Where("name = ? AND locked_by = ?", name, s.instance).
+		Updates(map[string]interface{}{
+			"locked_by":    "",
+			"locked_until": nil,
+		}).Error
+}
+
+// start 记录执行并在后台执行已认领的任务，记录失败时释放租约
+func (s *Scheduler) start(ctx context.Context, task *scheduledTask, trigger string) (*model.TaskRun, error) {
+	run := &model.TaskRun{
+		Task:      task.name,
+		Trigger:   trigger,
+		Instance:  s.instance,
+		Status:    model.TaskRunRunning,
+		StartedAt: time.Now(),
+	}
+	if trigger == model.TaskTriggerManual {
+		run.Actor = auditMetaFrom(ctx).Actor
+	}
+	if err := s.conn(ctx).Create(run).Error; err != nil {
+		if releaseErr := s.release(context.Background(), task.name); releaseErr != nil {
+			log.Printf("Failed to release task %s: %v", task.name, releaseErr)
+		}
+		return nil, err
+	}
+	
+	meta := model.AuditMeta{Actor: model.AuditAnonymous}
+	if trigger == model.TaskTriggerManual {
+		meta = auditMetaFrom(ctx)
+	}
+	
+	s.wg.Add(1)
+	go s.execute(WithAuditMeta(s.runCtx, meta), task, run)
+	return run, nil
+}
+
+// execute 执行任务并记录结果，执行期间定期续租，结束后释放租约
+func (s *Scheduler) execute(ctx context.Context, task *scheduledTask, run *model.TaskRun) {
+	defer s.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
+	done := make(chan struct{})
+	go s.renew(task.name, cancel, done)
+	
+	result, err := s.call(ctx, task)
+	close(done)
+	
+	if err := s.finish(context.Background(), run, result, err); err != nil {
+		log.Printf("Failed to record task %s run %d: %v", task.name, run.ID, err)
+	}
+	if err := s.release(context.Background(), task.name); err != nil {
+		log.Printf("Failed to release task %s: %v", task.name, err)
+	}
+}
+
+// call 调用任务的执行函数，panic 视为执行失败
+func (s *Scheduler) call(ctx context.Context, task *scheduledTask) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Task %s panicked: %v", task.name, r)
+			err = fmt.Errorf("任务执行异常: %v", r)
+		}
+	}()
+	return task.fn(ctx)
+}
+
+// renew 每隔租约的三分之一续租一次；租约已被其他实例接管时取消任务
+func (s *Scheduler) renew(name string, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(taskLease / 3)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			result := s.conn(context.Background()).Model(&model.ScheduledTask{}).
+				Where("name = ? AND locked_by = ?", name, s.instance).
+				Update("locked_until", time.Now().Add(taskLease))
+			if result.Error != nil {
+				log.Printf("Failed to renew task %s lease: %v", name, result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				log.Printf("Task %s lease lost, canceling", name)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// finish 记录执行结果
+func (s *Scheduler) finish(ctx context.Context, run *model.TaskRun, result interface{}, runErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      model.TaskRunSucceeded,
+		"finished_at": now,
+	}
+	if runErr != nil {
+		updates["status"] = model.TaskRunFailed
+		updates["error"] = truncate(runErr.Error(), 1000)
+		if s.runCtx.Err() != nil {
+			updates["error"] = truncate("因停机中断: "+runErr.Error(), 1000)
+		}
+		log.Printf("Task %s failed: %v", run.Task, runErr)
+	}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		updates["result"] = string(data)
+	}
+	
+	run.Status = updates["status"].(string)
+	run.FinishedAt = &now
+	return s.conn(ctx).Model(run).Updates(updates).Error
+}
+
+// reapRuns 把租约已过期的任务仍在执行中的记录标记为失败（执行它的进程崩溃或失联）
+func (s *Scheduler) reapRuns(ctx context.Context) (int64, error) {
+	now := time.Now()
+	held := s.conn(ctx).Model(&model.ScheduledTask{}).Select("name").
+		Where("locked_until >= ?", now)
+	result := s.conn(ctx).Model(&model.TaskRun{}).
+		Where("status = ? AND task NOT IN (?)", model.TaskRunRunning, held).
+		Updates(map[string]interface{}{
+			"status":      model.TaskRunFailed,
+			"error":       "执行任务的进程已退出",
+			"finished_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeRuns 删除开始时间早于保留期的执行记录，返回删除的数量
+func (s *Scheduler) PurgeRuns(ctx context.Context) (int64, error) {
+	result := s.conn(ctx).
+		Where("started_at < ? AND status <> ?", time.Now().Add(-s.retention), model.TaskRunRunning).
+		Delete(&model.TaskRun{})
+	return result.RowsAffected, result.Error
+}
+
+// Trigger 立即执行一次任务，不影响下次按时执行的时间；任务正在执行时返回 ErrTaskRunning
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*model.TaskRun, error) {
+	task, ok := s.tasks[name]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	
+	claimed, err := s.claim(ctx, task, false)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrTaskRunning
+	}
+	return s.start(ctx, task, model.TaskTriggerManual)
+}
+
+// GetTasks 获取全部定时任务的调度状态和最近一次执行
+func (s *Scheduler) GetTasks(ctx context.Context) ([]*model.ScheduledTaskResponse, error) {
+	var rows []*model.ScheduledTask
+	if err := s.conn(ctx).Clauses(dbresolver.Write).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	states := make(map[string]*model.ScheduledTask, len(rows))
+	for _, row := range rows {
+		states[row.Name] = row
+	}
+	
+	names := make([]string, 0, len(s.tasks))
+	for name := range s.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	now := time.Now()
+	responses := make([]*model.ScheduledTaskResponse, 0, len(names))
+	for _, name := range names {
+		response := &model.ScheduledTaskResponse{Name: name, Schedule: s.tasks[name].spec}
+		if state := states[name]; state != nil {
+			response.NextRunAt = state.NextRunAt
+			response.Running = state.LockedUntil != nil && state.LockedUntil.After(now)
+		}
+		
+		var last model.TaskRun
+		err := s.conn(ctx).Clauses(dbresolver.Write).Where("task = ?", name).Order("id DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			response.LastRun = last.ToResponse()
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// GetRuns 分页获取任务的执行记录，最新的在前
+func (s *Scheduler) GetRuns(ctx context.Context, name string, page, pageSize int) ([]*model.TaskRun, int64, error) {
+	if _, ok := s.tasks[name]; !ok {
+		return nil, 0, ErrTaskNotFound
+	}
+	
+	var runs []*model.TaskRun
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.TaskRun{}).Where("task = ?", name)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return runs, total, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+	"topService/internal/database"
+	"topService/internal/jwt"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionIssuer 本服务签发的会话令牌的 iss
+	sessionIssuer = "topService"
+	// sessionTouchInterval 会话最近使用时间的更新间隔，避免每次请求都写库
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength 会话记录的 User-Agent 最大长度
+	maxUserAgentLength = 255
+)
+
+// SessionClaims 会话令牌载荷
+type SessionClaims struct {
+	jwt.RegisteredClaims
+	Role      string `json:"role"`
+	TwoFactor bool   `json:"2fa,omitempty"` // 登录时是否通过了两步验证
+}
+
+// SessionService 签发和校验本服务的会话令牌（HS256 JWT）
+type SessionService struct {
+	db     *gorm.DB
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewSessionService(db *gorm.DB, secret string, ttl time.Duration) *SessionService {
+	return &SessionService{db: db, secret: []byte(secret), ttl: ttl}
+}
+
+func (s *SessionService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// newTokenID 生成随机的令牌 ID
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// IssueToken 为用户签发会话令牌并记录会话，twoFactor 表示本次登录是否通过了两步验证
+func (s *SessionService) IssueToken(ctx context.Context, user *model.User, twoFactor bool, meta model.SessionMeta) (string, time.Time, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	userAgent := meta.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &model.Session{
+		TokenID:    id,
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         meta.IP,
+		TwoFactor:  twoFactor,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.conn(ctx).Create(session).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	
+	claims := &SessionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    sessionIssuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+			ID:        id,
+		},
+		Role:      user.Role,
+		TwoFactor: twoFactor,
+	}
+	
+	token, err := jwt.SignHS256(claims, s.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Authenticate 校验会话令牌并返回当前用户和会话；会话被吊销、用户被删除或禁用后令牌立即失效
+func (s *SessionService) Authenticate(ctx context.Context, token, ip string) (*model.User, *model.Session, error) {
+	var claims SessionClaims
+	if err := jwt.VerifyHS256(token, s.secret, &claims); err != nil {
+		return nil, nil, ErrInvalidSession
+	}
+	if claims.Issuer != sessionIssuer || claims.Validate(time.Now()) != nil {
+		return nil, nil, ErrInvalidSession
+	}
+	
+	var session model.Session
+	if err := s.conn(ctx).Where("token_id = ?", claims.ID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidSession
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !session.Active(now) || strconv.FormatUint(uint64(session.UserID), 10) != claims.Subject {
+		return nil, nil, ErrInvalidSession
+	}
+	
+	var user model.User
+	if err := s.conn(ctx).First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidSession
+		}
+		return nil, nil, err
+	}
+	if user.Status != 1 {
+		return nil, nil, ErrInvalidSession
+	}
+	
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval || session.IP != ip {
+		if err := s.conn(ctx).Model(&session).UpdateColumns(map[string]interface{}{
+			"last_seen_at": now,
+			"ip":           ip,
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+		session.LastSeenAt = now
+		session.IP = ip
+	}
+	
+	return &user, &session, nil
+}
+
+// ListSessions 返回用户未过期且未吊销的会话，最近使用的在前
+func (s *SessionService) ListSessions(ctx context.Context, userID uint) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := s.conn(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 吊销用户的某个会话
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	result := s.conn(ctx).Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在")
+	}
+	return nil
+}
+
+// RevokeSessions 吊销用户的全部会话，exceptID 不为 0 时保留该会话，返回吊销数量
+func (s *SessionService) RevokeSessions(ctx context.Context, userID, exceptID uint) (int64, error) {
+	return revokeSessions(s.conn(ctx), userID, exceptID)
+}
+
+// PurgeExpired 删除过期超过一个会话有效期的会话记录
+func (s *SessionService) PurgeExpired(ctx context.Context) (int64, error) {
+	result := s.conn(ctx).Where("expires_at < ?", time.Now().Add(-s.ttl)).Delete(&model.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// revokeSessions 吊销用户的会话，供禁用用户、重置密码等在各自事务中调用
+func revokeSessions(db *gorm.DB, userID, exceptID uint) (int64, error) {
+	query := db.Model(&model.Session{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if exceptID != 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+	result := query.Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// ExpireTokens 删除过期的会话记录和一次性令牌，供定时任务 tokens.expire 调用，返回各表删除的行数
+func (s *SessionService) ExpireTokens(ctx context.Context) (map[string]int64, error) {
+	expired := make(map[string]int64, 2)
+	
+	sessions, err := s.PurgeExpired(ctx)
+	if err != nil {
+		return expired, err
+	}
+	expired["sessions"] = sessions
+	
+	result := s.conn(ctx).Where("expires_at < ?", time.Now()).Delete(&model.UserToken{})
+	if result.Error != nil {
+		return expired, result.Error
+	}
+	expired["user_tokens"] = result.RowsAffected
+	
+	return expired, nil
+}
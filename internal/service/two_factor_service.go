@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+	"topService/internal/totp"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// maxChallengeAttempts 登录第二步允许提交错误的次数，超过后需重新登录
+	maxChallengeAttempts = 5
+)
+
+// recoveryCodeAlphabet 恢复码字符集，去掉了易混淆的 0/1/l/o
+const recoveryCodeAlphabet = "23456789abcdefghijkmnpqrstuvwxyz"
+
+// TwoFactorService 基于 TOTP 的两步验证：绑定、恢复码和登录第二步
+type TwoFactorService struct {
+	db           *gorm.DB
+	tokens       *userTokens
+	throttle     *LoginThrottleService
+	issuer       string
+	challengeTTL time.Duration
+}
+
+func NewTwoFactorService(db *gorm.DB, throttle *LoginThrottleService, cfg *config.Config) *TwoFactorService {
+	return &TwoFactorService{
+		db:           db,
+		tokens:       newUserTokens(db, cfg.SessionSecret),
+		throttle:     throttle,
+		issuer:       cfg.TOTPIssuer,
+		challengeTTL: cfg.TwoFactorChallengeTTL,
+	}
+}
+
+func (s *TwoFactorService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// lockUser 加行锁读取用户，必须在事务内调用
+func (s *TwoFactorService) lockUser(ctx context.Context, userID uint) (*model.User, error) {
+	var user model.User
+	if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Enroll 生成新的 TOTP 密钥，确认前不生效；重复调用会替换尚未确认的密钥
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uint) (*model.TOTPEnrollResponse, error) {
+	var resp *model.TOTPEnrollResponse
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		user, err := s.lockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled() {
+			return ErrTwoFactorAlreadyEnabled
+		}
+		
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		if err := s.conn(ctx).Model(user).Update("totp_secret", secret).Error; err != nil {
+			return err
+		}
+		
+		resp = &model.TOTPEnrollResponse{
+			Secret: secret,
+			URI:    totp.URI(s.issuer, user.Email, secret),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return resp, nil
+}
+
+// Confirm 用验证器应用生成的动态码确认绑定，成功后启用两步验证并返回恢复码明文（只返回这一次）
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	var codes []string
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		user, err := s.lockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled() {
+			return ErrTwoFactorAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTwoFactorNotEnabled
+		}
+		
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		
+		before := user.ToResponse()
+		now := time.Now()
+		user.TOTPEnabledAt = &now
+		user.TOTPLastStep = step
+		user.Version++
+		if err := s.conn(ctx).Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": now,
+			"totp_last_step":  step,
+			"version":         user.Version,
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse()); err != nil {
+			return err
+		}
+		
+		codes, err = s.replaceRecoveryCodes(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码，旧恢复码全部作废
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	var codes []string
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		user, err := s.lockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled() {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := s.checkCode(ctx, user, code); err != nil {
+			return err
+		}
+		
+		codes, err = s.replaceRecoveryCodes(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return codes, nil
+}
+
+// Disable 校验动态码或恢复码后关闭两步验证
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		user, err := s.lockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled() {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := s.checkCode(ctx, user, code); err != nil {
+			return err
+		}
+		
+		return s.clear(ctx, user)
+	})
+}
+
+// Reset 管理员为丢失验证器和恢复码的用户关闭两步验证，用户需重新绑定
+func (s *TwoFactorService) Reset(ctx context.Context, userID uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		user, err := s.lockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPSecret == "" && !user.TOTPEnabled() {
+			return ErrTwoFactorNotEnabled
+		}
+		
+		return s.clear(ctx, user)
+	})
+}
+
+// Challenge 第一步登录通过后签发登录第二步令牌
+func (s *TwoFactorService) Challenge(ctx context.Context, user *model.User) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.challengeTTL)
+	token, err := s.tokens.issue(ctx, user, user.Email, model.TokenPurposeTwoFactor, s.challengeTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// VerifyChallenge 校验登录第二步令牌和动态码（或恢复码），通过后返回用户
+// 提交错误达到 maxChallengeAttempts 次后令牌作废，需要重新进行第一步登录；错误同时计入账号和 IP 的登录失败次数
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challenge, code, ip string) (*model.User, error) {
+	claims, err := s.tokens.verify(challenge, model.TokenPurposeTwoFactor)
+	if err != nil {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	
+	subjects := []string{"user:" + claims.Subject, throttleIPKey(ip)}
+	if err := s.throttle.Check(ctx, subjects...); err != nil {
+		return nil, err
+	}
+	
+	var user *model.User
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var record model.UserToken
+		err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", claims.ID, model.TokenPurposeTwoFactor, time.Now()).
+			First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidTwoFactorChallenge
+		}
+		if err != nil {
+			return err
+		}
+		
+		if user, err = s.lockUser(ctx, record.UserID); err != nil {
+			return err
+		}
+		if user.Status != 1 {
+			return ErrUserDisabled
+		}
+		if !user.TOTPEnabled() {
+			// 等待第二步期间两步验证被关闭，要求重新登录
+			return ErrInvalidTwoFactorChallenge
+		}
+		if err := s.checkCode(ctx, user, code); err != nil {
+			return err
+		}
+		
+		return s.conn(ctx).Model(&record).Update("used_at", time.Now()).Error
+	})
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		// 错误次数在事务外累计，避免随事务回滚
+		if countErr := s.recordFailedAttempt(ctx, claims.ID); countErr != nil {
+			return nil, countErr
+		}
+		if countErr := s.throttle.Fail(ctx, subjects...); countErr != nil {
+			return nil, countErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.throttle.Reset(ctx, subjects[0]); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// recordFailedAttempt 累计登录第二步的错误次数，达到上限后作废令牌
+func (s *TwoFactorService) recordFailedAttempt(ctx context.Context, tokenID string) error {
+	query := s.conn(ctx).Model(&model.UserToken{}).Where("token_id = ? AND used_at IS NULL", tokenID)
+	if err := query.Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return err
+	}
+	
+	return s.conn(ctx).Model(&model.UserToken{}).
+		Where("token_id = ? AND used_at IS NULL AND attempts >= ?", tokenID, maxChallengeAttempts).
+		Update("used_at", time.Now()).Error
+}
+
+// checkCode 校验动态码或恢复码，必须在持有用户行锁的事务内调用
+// 动态码的时间步不能早于或等于上次使用的时间步，恢复码使用后作废
+func (s *TwoFactorService) checkCode(ctx context.Context, user *model.User, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		if step <= user.TOTPLastStep {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return s.conn(ctx).Model(user).Update("totp_last_step", step).Error
+	}
+	
+	result := s.conn(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// clear 关闭两步验证并删除恢复码，修改记入审计日志和 user.updated 事件
+func (s *TwoFactorService) clear(ctx context.Context, user *model.User) error {
+	before := user.ToResponse()
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	user.Version++
+	if err := s.conn(ctx).Model(user).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+		"version":         user.Version,
+	}).Error; err != nil {
+		return err
+	}
+	if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse()); err != nil {
+		return err
+	}
+	
+	return s.conn(ctx).Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的一组，返回明文
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	if err := s.conn(ctx).Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	
+	codes := make([]string, recoveryCodeCount)
+	records := make([]*model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = &model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}
+	}
+	
+	if err := s.conn(ctx).Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode 生成形如 xxxxx-xxxxx 的恢复码
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	
+	code := make([]byte, 0, 11)
+	for i, b := range buf {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// hashRecoveryCode 忽略大小写、空格和连字符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
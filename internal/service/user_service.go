@@ -1,30 +1,54 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"time"
+	"topService/internal/database"
+	"topService/internal/mailer"
 	"topService/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db   *gorm.DB
+	jobs *JobService
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *gorm.DB, jobs *JobService) *UserService {
+	return &UserService{db: db, jobs: jobs}
 }
 
-// CreateUser 创建用户
-func (s *UserService) CreateUser(req *model.UserCreateRequest) (*model.User, error) {
-	user := &model.User{
+// conn 返回当前请求使用的数据库连接，处于事务中时返回事务连接
+func (s *UserService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// newUser 根据创建请求构造用户
+func newUser(req *model.UserCreateRequest) *model.User {
+	return &model.User{
 		Username: req.Username,
 		Email:    req.Email,
 		Phone:    req.Phone,
 		Status:   1,
+		Role:     model.RoleUser,
+		Version:  1,
 	}
+}
+
+// CreateUser 创建用户
+func (s *UserService) CreateUser(ctx context.Context, req *model.UserCreateRequest) (*model.User, error) {
+	user := newUser(req)
 	
-	if err := s.db.Create(user).Error; err != nil {
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Create(user).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionCreate, model.AuditEntityUser, user.ID, nil, user.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -32,9 +56,9 @@ func (s *UserService) CreateUser(req *model.UserCreateRequest) (*model.User, err
 }
 
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(id uint) (*model.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.conn(ctx).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
 		}
@@ -44,18 +68,28 @@ func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
-// GetUsers 获取用户列表
-func (s *UserService) GetUsers(page, pageSize int, keyword string) ([]*model.User, int64, error) {
-	var users []*model.User
-	var total int64
-	
-	query := s.db.Model(&model.User{})
+// listQuery 构造用户列表查询条件，列表与导出共用
+func (s *UserService) listQuery(ctx context.Context, keyword string, includeDeleted bool) *gorm.DB {
+	query := s.conn(ctx).Model(&model.User{})
+	if includeDeleted {
+		query = query.Unscoped()
+	}
 	
 	// 搜索条件
 	if keyword != "" {
 		query = query.Where("username LIKE ? OR email LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
 	}
 	
+	return query
+}
+
+// GetUsers 获取用户列表，includeDeleted 为 true 时包含已软删除的用户
+func (s *UserService) GetUsers(ctx context.Context, page, pageSize int, keyword string, includeDeleted bool) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+	
+	query := s.listQuery(ctx, keyword, includeDeleted)
+	
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -70,47 +104,274 @@ func (s *UserService) GetUsers(page, pageSize int, keyword string) ([]*model.Use
 	return users, total, nil
 }
 
-// UpdateUser 更新用户
-func (s *UserService) UpdateUser(id uint, req *model.UserUpdateRequest) (*model.User, error) {
+// UpdateUser 全量更新用户（PUT）
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *UserService) UpdateUser(ctx context.Context, id, version uint, req *model.UserUpdateRequest) (*model.User, error) {
+	return s.PatchUser(ctx, id, version, func(current *model.UserUpdateRequest) error {
+		*current = *req
+		return nil
+	})
+}
+
+// PatchUser 局部更新用户（PATCH）
+// patch 在行锁内基于当前数据修改全量更新请求，返回错误时整个更新回滚
+// 修改邮箱不会立即生效：新邮箱记为待确认并向其发送确认邮件，同时通知原邮箱，确认后才替换；未认证的请求不能修改邮箱
+func (s *UserService) PatchUser(ctx context.Context, id, version uint, patch func(req *model.UserUpdateRequest) error) (*model.User, error) {
 	var user model.User
-	if err := s.db.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		// 加行锁读取，防止并发更新互相覆盖；加锁查询始终路由到主库
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("用户不存在")
+			}
+			return err
+		}
+		if version != 0 && user.Version != version {
+			return ErrVersionConflict
 		}
+		
+		req := user.ToUpdateRequest()
+		if err := patch(req); err != nil {
+			return err
+		}
+		before := user.ToResponse()
+		
+		emailChanged := req.Email != user.Email && req.Email != user.PendingEmail
+		if emailChanged {
+			if err := s.checkEmailChange(ctx, user.ID, req.Email); err != nil {
+				return err
+			}
+			user.PendingEmail = req.Email
+		}
+		
+		// 全量替换字段，邮箱除外
+		user.Username = req.Username
+		user.Phone = req.Phone
+		disabled := user.Status == 1 && *req.Status != 1
+		enabled := user.Status != 1 && *req.Status == 1
+		user.Status = *req.Status
+		user.Version++
+		
+		if err := s.conn(ctx).Save(&user).Error; err != nil {
+			return err
+		}
+		if err := recordChange(ctx, s.conn(ctx), model.AuditActionUpdate, model.AuditEntityUser, user.ID, before, user.ToResponse()); err != nil {
+			return err
+		}
+		
+		if emailChanged {
+			if err := s.sendEmailChange(ctx, &user); err != nil {
+				return err
+			}
+		}
+		
+		// 禁用用户时立即吊销其全部会话，重新启用后需要重新登录
+		if disabled {
+			if _, err := revokeSessions(s.conn(ctx), user.ID, 0); err != nil {
+				return err
+			}
+		}
+		
+		// 启用状态变化时额外写入 user.disabled / user.enabled 事件
+		if disabled || enabled {
+			eventType := model.EventUserEnabled
+			if disabled {
+				eventType = model.EventUserDisabled
+			}
+			return recordEvent(s.conn(ctx), eventType, model.AuditEntityUser, user.ID, user.ToResponse(), map[string]model.FieldChange{
+				"status": {Before: before.Status, After: user.Status},
+			})
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	
-	// 更新字段
-	if req.Username != "" {
-		user.Username = req.Username
+	return &user, nil
+}
+
+// checkEmailChange 检查调用者能否把用户的邮箱改为 email
+func (s *UserService) checkEmailChange(ctx context.Context, id uint, email string) error {
+	// 否则任何人都能把他人的邮箱改成自己的，再通过重置密码接管账号
+	if auditMetaFrom(ctx).Actor == model.AuditAnonymous {
+		return ErrEmailChangeForbidden
 	}
-	if req.Email != "" {
-		user.Email = req.Email
+	
+	var count int64
+	if err := s.conn(ctx).Model(&model.User{}).Where("email = ? AND id <> ?", email, id).Count(&count).Error; err != nil {
+		return err
 	}
-	if req.Phone != "" {
-		user.Phone = req.Phone
+	if count > 0 {
+		return ErrEmailTaken
 	}
-	if req.Status != nil {
-		user.Status = *req.Status
+	return nil
+}
+
+// sendEmailChange 在同一事务中创建发往新邮箱的确认邮件和发往原邮箱的通知邮件
+func (s *UserService) sendEmailChange(ctx context.Context, user *model.User) error {
+	for _, template := range []string{emailChange, emailChangeNotice} {
+		payload := &emailPayload{Template: template, UserID: user.ID, Email: user.PendingEmail, Locale: mailer.DefaultLocale}
+		if _, err := s.jobs.Enqueue(ctx, model.JobSendEmail, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteUser 删除用户
+// version 为客户端期望的版本号，为 0 时不做校验
+func (s *UserService) DeleteUser(ctx context.Context, id, version uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var user model.User
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("用户不存在")
+			}
+			return err
+		}
+		if version != 0 && user.Version != version {
+			return ErrVersionConflict
+		}
+		before := user.ToResponse()
+		
+		// 软删除时同时写入 deleted_id，释放用户名和邮箱
+		now := time.Now()
+		if err := s.conn(ctx).Model(&user).Updates(map[string]interface{}{
+			"deleted_at": now,
+			"deleted_id": gorm.Expr("id"),
+		}).Error; err != nil {
+			return err
+		}
+		user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		
+		return recordChange(ctx, s.conn(ctx), model.AuditActionDelete, model.AuditEntityUser, user.ID, before, user.ToResponse())
+	})
+}
+
+// GetDeletedUsers 获取回收站中的用户
+func (s *UserService) GetDeletedUsers(ctx context.Context, page, pageSize int) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+	
+	query := s.conn(ctx).Unscoped().Model(&model.User{}).Where("deleted_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 	
-	if err := s.db.Save(&user).Error; err != nil {
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("deleted_at DESC").Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return users, total, nil
+}
+
+// RestoreUser 从回收站恢复用户
+func (s *UserService) RestoreUser(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该用户")
+			}
+			return err
+		}
+		
+		// 用户名或邮箱已被其他用户占用时无法恢复
+		var count int64
+		if err := s.conn(ctx).Model(&model.User{}).
+			Where("username = ? OR email = ?", user.Username, user.Email).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("用户名或邮箱已被占用，无法恢复")
+		}
+		
+		before := user.ToResponse()
+		user.DeletedAt = gorm.DeletedAt{}
+		user.DeletedID = 0
+		user.Version++
+		if err := s.conn(ctx).Unscoped().Save(&user).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionRestore, model.AuditEntityUser, user.ID, before, user.ToResponse())
+	})
+	if err != nil {
 		return nil, err
 	}
 	
 	return &user, nil
 }
 
-// DeleteUser 删除用户
-func (s *UserService) DeleteUser(id uint) error {
-	result := s.db.Delete(&model.User{}, id)
-	if result.Error != nil {
-		return result.Error
+// PurgeUser 彻底删除回收站中的用户，同时删除其会话、身份关联、一次性令牌、恢复码和登录失败计数
+func (s *UserService) PurgeUser(ctx context.Context, id uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var user model.User
+		if err := s.conn(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("回收站中不存在该用户")
+			}
+			return err
+		}
+		
+		if err := s.conn(ctx).Unscoped().Delete(&user).Error; err != nil {
+			return err
+		}
+		for _, value := range []interface{}{&model.Session{}, &model.UserIdentity{}, &model.UserToken{}, &model.RecoveryCode{}} {
+			if err := s.conn(ctx).Where("user_id = ?", user.ID).Delete(value).Error; err != nil {
+				return err
+			}
+		}
+		if err := s.conn(ctx).Where("subject = ?", throttleUserKey(user.ID)).Delete(&model.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		return recordChange(ctx, s.conn(ctx), model.AuditActionPurge, model.AuditEntityUser, user.ID, user.ToResponse(), nil)
+	})
+}
+
+// CreateUsers 批量创建用户，返回与请求一一对应的错误
+// atomic 为 true 时全部成功才提交，否则跳过失败的条目
+func (s *UserService) CreateUsers(ctx context.Context, reqs []*model.UserCreateRequest, atomic bool) ([]*model.User, []error) {
+	users := make([]*model.User, len(reqs))
+	for i, req := range reqs {
+		users[i] = newUser(req)
 	}
 	
-	if result.RowsAffected == 0 {
-		return errors.New("用户不存在")
-	}
+	errs := insertBatch(ctx, s.db, len(users), atomic, func(db *gorm.DB, from, to int) error {
+		if err := db.CreateInBatches(users[from:to], batchSize).Error; err != nil {
+			return err
+		}
+		return recordCreates(ctx, db, model.AuditEntityUser, to-from, func(i int) (uint, interface{}) {
+			return users[from+i].ID, users[from+i].ToResponse()
+		})
+	})
 	
-	return nil
-}
\ No newline at end of file
+	return users, errs
+}
+
+// UpdateUsers 批量全量更新用户，返回与条目一一对应的错误
+func (s *UserService) UpdateUsers(ctx context.Context, items []*model.UserBatchUpdateItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		_, err := s.UpdateUser(ctx, items[i].ID, items[i].Version, &items[i].UserUpdateRequest)
+		return err
+	})
+}
+
+// DeleteUsers 批量删除用户，返回与条目一一对应的错误
+func (s *UserService) DeleteUsers(ctx context.Context, items []*model.BatchDeleteItem, atomic bool) []error {
+	return runBatch(ctx, s.db, len(items), atomic, func(ctx context.Context, i int) error {
+		return s.DeleteUser(ctx, items[i].ID, items[i].Version)
+	})
+}
+
+// ExportUsers 按列表筛选条件分批读取全部用户，每批交给 fn 处理，避免一次性加载到内存
+func (s *UserService) ExportUsers(ctx context.Context, keyword string, includeDeleted bool, fn func(users []*model.User) error) error {
+	var batch []*model.User
+	return s.listQuery(ctx, keyword, includeDeleted).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
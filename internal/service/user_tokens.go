@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"topService/internal/database"
+	"topService/internal/jwt"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userTokenClaims 一次性令牌载荷，aud 为令牌用途
+type userTokenClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email,omitempty"`
+}
+
+// userTokens 签发和核销一次性令牌（邮件链接、登录第二步等）
+// 令牌本身为 HS256 JWT，数据库中记录 jti 保证只能使用一次
+type userTokens struct {
+	db     *gorm.DB
+	secret []byte
+}
+
+func newUserTokens(db *gorm.DB, secret string) *userTokens {
+	return &userTokens{db: db, secret: []byte(secret)}
+}
+
+func (t *userTokens) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, t.db)
+}
+
+// issue 签发一次性令牌并记录 jti，email 为令牌绑定的邮箱，邮箱变化后核销方据此判断令牌失效
+func (t *userTokens) issue(ctx context.Context, user *model.User, email, purpose string, ttl time.Duration) (string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	
+	now := time.Now()
+	record := &model.UserToken{
+		TokenID:   id,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := t.conn(ctx).Create(record).Error; err != nil {
+		return "", err
+	}
+	
+	claims := &userTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.Audience{purpose},
+			IssuedAt:  now.Unix(),
+			ExpiresAt: record.ExpiresAt.Unix(),
+			ID:        id,
+		},
+		Email: email,
+	}
+	return jwt.SignHS256(claims, t.secret)
+}
+
+// verify 只校验令牌签名、用途和有效期，不核销
+func (t *userTokens) verify(token, purpose string) (*userTokenClaims, error) {
+	var claims userTokenClaims
+	if err := jwt.VerifyHS256(token, t.secret, &claims); err != nil {
+		return nil, ErrInvalidUserToken
+	}
+	if !claims.Audience.Contains(purpose) || claims.ExpiresAt == 0 || claims.Validate(time.Now()) != nil {
+		return nil, ErrInvalidUserToken
+	}
+	return &claims, nil
+}
+
+// consume 校验令牌并原子地标记为已使用，返回令牌所属用户
+func (t *userTokens) consume(ctx context.Context, token, purpose string) (*model.User, *userTokenClaims, error) {
+	claims, err := t.verify(token, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	now := time.Now()
+	result := t.conn(ctx).Model(&model.UserToken{}).
+		Where("token_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", claims.ID, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, nil, ErrInvalidUserToken
+	}
+	
+	var user model.User
+	if err := t.conn(ctx).First(&user, claims.Subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidUserToken
+		}
+		return nil, nil, err
+	}
+	
+	return &user, claims, nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// videoCheckBatchSize 每批检查的电影数，每批结束后记录进度
+	videoCheckBatchSize = 100
+	// videoCheckConcurrency 同时检查的播放地址数
+	videoCheckConcurrency = 8
+	// m3u8Signature M3u8 播放列表的文件头
+	m3u8Signature = "#EXTM3U"
+)
+
+// videoCheckPayload movie.check_video 任务参数，movie_ids 为空时检查全部有播放地址的电影
+type videoCheckPayload struct {
+	MovieIDs []uint `json:"movie_ids,omitempty"`
+}
+
+// videoCheckResult movie.check_video 任务结果
+type videoCheckResult struct {
+	OK     int `json:"ok"`
+	Broken int `json:"broken"`
+}
+
+// VideoCheckService 检查电影播放地址（M3u8）是否可用
+type VideoCheckService struct {
+	db         *gorm.DB
+	jobService *JobService
+	client     *http.Client
+}
+
+func NewVideoCheckService(db *gorm.DB, jobService *JobService, cfg *config.Config) *VideoCheckService {
+	return &VideoCheckService{
+		db:         db,
+		jobService: jobService,
+		client:     &http.Client{Timeout: cfg.VideoCheckTimeout},
+	}
+}
+
+func (s *VideoCheckService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// StartCheck 创建检查任务，movieIDs 为空时检查全部有播放地址的电影
+func (s *VideoCheckService) StartCheck(ctx context.Context, movieIDs []uint) (*model.Job, error) {
+	return s.jobService.Enqueue(ctx, model.JobCheckVideo, &videoCheckPayload{MovieIDs: movieIDs}, nil)
+}
+
+// GetChecks 分页查询检查结果，status 为空时返回全部
+func (s *VideoCheckService) GetChecks(ctx context.Context, status string, page, pageSize int) ([]*model.MovieVideoCheck, int64, error) {
+	var checks []*model.MovieVideoCheck
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.MovieVideoCheck{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("movie_id").Find(&checks).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return checks, total, nil
+}
+
+// RunCheckJob 执行 movie.check_video 任务，按电影 ID 顺序分批检查，重试时从上次记录的进度继续
+func (s *VideoCheckService) RunCheckJob(ctx context.Context, run *JobRun) (interface{}, error) {
+	var payload videoCheckPayload
+	if err := run.Bind(&payload); err != nil {
+		return nil, err
+	}
+	
+	query := s.conn(ctx).Model(&model.Movie{}).Where("m3u8 <> ''")
+	if len(payload.MovieIDs) > 0 {
+		query = query.Where("id IN ?", payload.MovieIDs)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	
+	result := &videoCheckResult{}
+	processed := run.Job.Processed
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		
+		var movies []*model.Movie
+		if err := query.Select("id", "m3u8").Order("id").Offset(processed).Limit(videoCheckBatchSize).Find(&movies).Error; err != nil {
+			return nil, err
+		}
+		if len(movies) == 0 {
+			break
+		}
+		
+		checks := s.CheckMovies(ctx, movies)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := s.conn(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&checks).Error; err != nil {
+			return nil, err
+		}
+		for _, check := range checks {
+			if check.Status == model.VideoStatusOK {
+				result.OK++
+			} else {
+				result.Broken++
+			}
+		}
+		
+		processed += len(movies)
+		if err := run.Progress(ctx, processed, int(total), result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CheckMovies 并发检查一批电影的播放地址
+func (s *VideoCheckService) CheckMovies(ctx context.Context, movies []*model.Movie) []*model.MovieVideoCheck {
+	checks := make([]*model.MovieVideoCheck, len(movies))
+	sem := make(chan struct{}, videoCheckConcurrency)
+	var wg sync.WaitGroup
+	for i, movie := range movies {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, movie *model.Movie) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			checks[i] = s.check(ctx, movie)
+		}(i, movie)
+	}
+	wg.Wait()
+	return checks
+}
+
+// check 请求播放地址：返回 2xx 且内容以 #EXTM3U 开头视为可用
+func (s *VideoCheckService) check(ctx context.Context, movie *model.Movie) *model.MovieVideoCheck {
+	check := &model.MovieVideoCheck{
+		MovieID:   movie.ID,
+		URL:       movie.M3u8,
+		Status:    model.VideoStatusBroken,
+		CheckedAt: time.Now(),
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, movie.M3u8, nil)
+	if err != nil {
+		check.Error = truncate(err.Error(), 500)
+		return check
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		check.Error = truncate(err.Error(), 500)
+		return check
+	}
+	defer resp.Body.Close()
+	
+	check.HTTPStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		check.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return check
+	}
+	
+	head := make([]byte, 64)
+	n, _ := io.ReadFull(resp.Body, head)
+	if !bytes.HasPrefix(bytes.TrimLeft(head[:n], "\ufeff \t\r\n"), []byte(m3u8Signature)) {
+		check.Error = "不是 M3u8 播放列表"
+		return check
+	}
+	
+	check.Status = model.VideoStatusOK
+	return check
+}
@@ -0,0 +1,532 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"topService/internal/config"
+	"topService/internal/database"
+	"topService/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// webhookSecretPrefix 签名密钥前缀
+	webhookSecretPrefix = "whsec_"
+	// dispatchBatchSize 每轮最多分发的事件数
+	dispatchBatchSize = 200
+	// maxResponseBodyLog 投递记录中保存的响应内容长度上限
+	maxResponseBodyLog = 500
+	// webhookPurgeInterval 清理过期事件和投递记录的间隔
+	webhookPurgeInterval = time.Hour
+)
+
+// Webhook 请求头
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookService 管理 Webhook，把发件箱中的领域事件投递给订阅方
+// 事件先按订阅生成投递记录，投递失败按指数退避重试，次数用完后进入死信状态
+type WebhookService struct {
+	db          *gorm.DB
+	client      *http.Client
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	concurrency int
+	retention   time.Duration
+}
+
+func NewWebhookService(db *gorm.DB, cfg *config.Config) *WebhookService {
+	concurrency := cfg.WebhookConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &WebhookService{
+		db:          db,
+		client:      &http.Client{Timeout: cfg.WebhookTimeout},
+		maxAttempts: cfg.WebhookMaxAttempts,
+		backoffBase: cfg.WebhookBackoffBase,
+		backoffMax:  cfg.WebhookBackoffMax,
+		concurrency: concurrency,
+		retention:   cfg.EventRetention,
+	}
+}
+
+func (s *WebhookService) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, s.db)
+}
+
+// generateWebhookSecret 生成新的签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(buf), nil
+}
+
+// SignWebhook 计算签名：HMAC-SHA256(secret, timestamp + "." + body)，十六进制编码
+// 接收方用同样的方式计算并与 X-Webhook-Signature 中 sha256= 之后的部分比较，同时校验时间戳防止重放
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateEvents 校验订阅的事件类型并去重
+func validateEvents(events []string) (string, error) {
+	seen := make(map[string]bool, len(events))
+	list := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !model.ValidEventPattern(event) {
+			return "", fmt.Errorf("未知的事件类型: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			list = append(list, event)
+		}
+	}
+	return strings.Join(list, ","), nil
+}
+
+// CreateWebhook 创建 Webhook，返回记录和签名密钥
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *model.WebhookCreateRequest, createdBy string) (*model.Webhook, string, error) {
+	events, err := validateEvents(req.Events)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	
+	webhook := &model.Webhook{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      events,
+		Secret:      secret,
+		Active:      true,
+		CreatedBy:   createdBy,
+	}
+	if err := s.conn(ctx).Create(webhook).Error; err != nil {
+		return nil, "", err
+	}
+	
+	return webhook, secret, nil
+}
+
+// GetWebhookByID 根据ID获取 Webhook
+func (s *WebhookService) GetWebhookByID(ctx context.Context, id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := s.conn(ctx).First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	
+	return &webhook, nil
+}
+
+// GetWebhooks 获取 Webhook 列表
+func (s *WebhookService) GetWebhooks(ctx context.Context, page, pageSize int) ([]*model.Webhook, int64, error) {
+	var webhooks []*model.Webhook
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.Webhook{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&webhooks).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return webhooks, total, nil
+}
+
+// UpdateWebhook 修改 Webhook 的地址、说明、订阅的事件或启用状态
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id uint, req *model.WebhookUpdateRequest) (*model.Webhook, error) {
+	webhook, err := s.GetWebhookByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	updates := make(map[string]interface{})
+	if req.URL != nil {
+		updates["url"] = *req.URL
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Events != nil {
+		events, err := validateEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		updates["events"] = events
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if len(updates) == 0 {
+		return webhook, nil
+	}
+	
+	if err := s.conn(ctx).Model(webhook).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetWebhookByID(ctx, id)
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		result := s.conn(ctx).Delete(&model.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return s.conn(ctx).Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+	})
+}
+
+// RotateWebhookSecret 更换签名密钥，之后的投递立即使用新密钥签名
+func (s *WebhookService) RotateWebhookSecret(ctx context.Context, id uint) (*model.Webhook, string, error) {
+	webhook, err := s.GetWebhookByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.conn(ctx).Model(webhook).Update("secret", secret).Error; err != nil {
+		return nil, "", err
+	}
+	
+	return webhook, secret, nil
+}
+
+// GetDeliveries 获取 Webhook 的投递记录，status 为空时不限状态
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID uint, status string, page, pageSize int) ([]*model.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhookByID(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	
+	var deliveries []*model.WebhookDelivery
+	var total int64
+	
+	query := s.conn(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return deliveries, total, nil
+}
+
+// RetryDelivery 重新投递（通常用于死信），重置尝试次数并立即进入投递队列
+func (s *WebhookService) RetryDelivery(ctx context.Context, webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("webhook_id = ?", webhookID).First(&delivery, deliveryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDeliveryNotFound
+			}
+			return err
+		}
+		if delivery.Status == model.DeliveryPending {
+			return ErrDeliveryPending
+		}
+		
+		delivery.Status = model.DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		return s.conn(ctx).Model(&delivery).Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &delivery, nil
+}
+
+// Dispatch 为尚未分发的事件按订阅生成投递记录，返回处理的事件数
+// 事件按 ID 顺序加锁处理，多实例部署时不会重复分发
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	var count int
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var events []*model.OutboxEvent
+		if err := s.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("dispatched_at IS NULL").Order("id").Limit(dispatchBatchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		
+		var webhooks []*model.Webhook
+		if err := s.conn(ctx).Where("active = ?", true).Find(&webhooks).Error; err != nil {
+			return err
+		}
+		
+		now := time.Now()
+		ids := make([]uint, len(events))
+		var deliveries []*model.WebhookDelivery
+		for i, event := range events {
+			ids[i] = event.ID
+			for _, webhook := range webhooks {
+				if !webhook.Subscribes(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, &model.WebhookDelivery{
+					WebhookID:     webhook.ID,
+					EventID:       event.ID,
+					EventType:     event.Type,
+					Status:        model.DeliveryPending,
+					NextAttemptAt: now,
+				})
+			}
+		}
+		
+		if len(deliveries) > 0 {
+			if err := s.conn(ctx).CreateInBatches(deliveries, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		count = len(events)
+		return s.conn(ctx).Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("dispatched_at", now).Error
+	})
+	return count, err
+}
+
+// DeliverDue 投递到期的记录，返回本轮投递的数量
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	var due []*model.WebhookDelivery
+	if err := s.conn(ctx).Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at").Limit(s.concurrency * 4).Find(&due).Error; err != nil {
+		return 0, err
+	}
+	
+	// 以尝试次数为条件认领，并把下次尝试时间推到超时之后，避免多个实例同时投递同一条记录
+	lease := now.Add(2 * s.client.Timeout)
+	var claimed []*model.WebhookDelivery
+	for _, delivery := range due {
+		result := s.conn(ctx).Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND attempts = ?", delivery.ID, model.DeliveryPending, delivery.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":        delivery.Attempts + 1,
+				"last_attempt_at": now,
+				"next_attempt_at": lease,
+			})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 1 {
+			delivery.Attempts++
+			delivery.LastAttemptAt = &now
+			claimed = append(claimed, delivery)
+		}
+	}
+	
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range claimed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := s.deliver(ctx, delivery); err != nil {
+				log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	
+	return len(claimed), nil
+}
+
+// deliver 发送一次投递并记录结果
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) error {
+	var webhook model.Webhook
+	if err := s.conn(ctx).First(&webhook, delivery.WebhookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.finish(ctx, delivery, 0, "", errors.New("Webhook不存在"), true)
+		}
+		return err
+	}
+	if !webhook.Active {
+		return s.finish(ctx, delivery, 0, "", errors.New("Webhook已停用"), true)
+	}
+	
+	var event model.OutboxEvent
+	if err := s.conn(ctx).First(&event, delivery.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.finish(ctx, delivery, 0, "", errors.New("事件已过期清理"), true)
+		}
+		return err
+	}
+	
+	body, err := json.Marshal(event.Message())
+	if err != nil {
+		return err
+	}
+	status, responseBody, err := s.send(ctx, &webhook, delivery, body)
+	return s.finish(ctx, delivery, status, responseBody, err, false)
+}
+
+// send 以 POST 发送事件，非 2xx 响应视为失败
+func (s *WebhookService) send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "topService-Webhook/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(webhook.Secret, timestamp, body))
+	
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBodyLog))
+	responseBody := strings.ToValidUTF8(string(data), "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, responseBody, fmt.Errorf("对方返回 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, responseBody, nil
+}
+
+// finish 记录投递结果：成功、按退避时间重试或进入死信；permanent 为 true 时不再重试
+func (s *WebhookService) finish(ctx context.Context, delivery *model.WebhookDelivery, status int, responseBody string, deliverErr error, permanent bool) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"response_status": status,
+		"response_body":   responseBody,
+		"error":           "",
+	}
+	
+	switch {
+	case deliverErr == nil:
+		updates["status"] = model.DeliverySucceeded
+		updates["delivered_at"] = now
+	case permanent || delivery.Attempts >= s.maxAttempts:
+		updates["status"] = model.DeliveryDead
+		updates["error"] = truncate(deliverErr.Error(), 500)
+	default:
+		updates["next_attempt_at"] = now.Add(s.backoff(delivery.Attempts))
+		updates["error"] = truncate(deliverErr.Error(), 500)
+	}
+	
+	return s.conn(ctx).Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+}
+
+// backoff 第 attempts 次失败后的等待时长：backoffBase * 2^(attempts-1)，不超过 backoffMax
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	return backoffDelay(s.backoffBase, s.backoffMax, attempts)
+}
+
+// truncate 按字符截断字符串
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
+
+// PurgeExpired 删除超过保留期的已分发事件和已结束的投递记录
+func (s *WebhookService) PurgeExpired(ctx context.Context) error {
+	before := time.Now().Add(-s.retention)
+	if err := s.conn(ctx).Where("dispatched_at < ?", before).Delete(&model.OutboxEvent{}).Error; err != nil {
+		return err
+	}
+	return s.conn(ctx).Where("status <> ? AND updated_at < ?", model.DeliveryPending, before).
+		Delete(&model.WebhookDelivery{}).Error
+}
+
+// Run 按 interval 分发事件并投递到期的记录，ctx 取消时退出；interval 不大于 0 时不启动
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Dispatch(ctx); err != nil {
+				log.Printf("Failed to dispatch events: %v", err)
+			}
+			if _, err := s.DeliverDue(ctx); err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+			if time.Since(lastPurge) >= webhookPurgeInterval {
+				lastPurge = time.Now()
+				if err := s.PurgeExpired(ctx); err != nil {
+					log.Printf("Failed to purge events: %v", err)
+				}
+			}
+		}
+	}
+}
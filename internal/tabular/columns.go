@@ -0,0 +1,54 @@
+package tabular
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Columns 导出列，来自响应结构体的 JSON 字段
+type Columns struct {
+	Names   []string
+	indexes []int
+}
+
+// SelectColumns 按 fields（逗号分隔的 JSON 字段名）选择导出列，为空时导出全部字段
+func SelectColumns(responseType reflect.Type, fields string) (*Columns, error) {
+	available := make(map[string]int, responseType.NumField())
+	all := &Columns{}
+	for i := 0; i < responseType.NumField(); i++ {
+		name := strings.Split(responseType.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		available[name] = i
+		all.Names = append(all.Names, name)
+		all.indexes = append(all.indexes, i)
+	}
+	
+	if fields == "" {
+		return all, nil
+	}
+	
+	selected := &Columns{}
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		index, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的导出字段: %s", name)
+		}
+		selected.Names = append(selected.Names, name)
+		selected.indexes = append(selected.indexes, index)
+	}
+	return selected, nil
+}
+
+// Values 取出响应结构体（指针）中导出列的值
+func (c *Columns) Values(response interface{}) []interface{} {
+	value := reflect.ValueOf(response).Elem()
+	values := make([]interface{}, len(c.indexes))
+	for i, index := range c.indexes {
+		values[i] = value.Field(index).Interface()
+	}
+	return values
+}
@@ -0,0 +1,223 @@
+package tabular
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format 表格文件格式
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+	FormatXLSX  Format = "xlsx"
+)
+
+// ParseFormat 解析格式名称，支持常见别名
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("不支持的文件格式: %s", name)
+	}
+}
+
+// DetectFormat 根据文件扩展名推断格式
+func DetectFormat(filename string) (Format, error) {
+	return ParseFormat(filepath.Ext(filename))
+}
+
+// Record 文件中的一行数据
+type Record struct {
+	Line   int               // 在文件中的行号，从 1 开始（CSV/XLSX 第 1 行为表头）
+	Fields map[string]string // 字段名 -> 原始值
+}
+
+// Mapping 把列名（JSON Lines 为键名）映射为字段名，返回 false 表示忽略该列
+type Mapping func(column string) (field string, ok bool)
+
+// ReadAll 读取文件中的全部记录，空行会被跳过
+// maxRows 大于 0 时限制最大行数，超过时返回错误
+func ReadAll(r io.Reader, format Format, mapping Mapping, maxRows int) ([]Record, error) {
+	var (
+		records []Record
+		err     error
+	)
+	
+	switch format {
+	case FormatCSV:
+		records, err = readCSV(r, mapping, maxRows)
+	case FormatJSONL:
+		records, err = readJSONL(r, mapping, maxRows)
+	case FormatXLSX:
+		records, err = readXLSX(r, mapping, maxRows)
+	default:
+		err = fmt.Errorf("不支持的文件格式: %s", format)
+	}
+	
+	return records, err
+}
+
+var errTooManyRows = errors.New("文件行数超过限制")
+
+func readCSV(r io.Reader, mapping Mapping, maxRows int) ([]Record, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("文件为空")
+		}
+		return nil, err
+	}
+	// 去掉 Excel 导出的 UTF-8 BOM
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	fields := mapColumns(header, mapping)
+	
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record, ok := toRecord(line, fields, row); ok {
+			if maxRows > 0 && len(records) >= maxRows {
+				return nil, errTooManyRows
+			}
+			records = append(records, record)
+		}
+	}
+	
+	return records, nil
+}
+
+func readXLSX(r io.Reader, mapping Mapping, maxRows int) ([]Record, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	// 只读取第一个工作表
+	rows, err := file.Rows(file.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var (
+		fields  []string
+		records []Record
+	)
+	for line := 1; rows.Next(); line++ {
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			fields = mapColumns(row, mapping)
+			continue
+		}
+		if record, ok := toRecord(line, fields, row); ok {
+			if maxRows > 0 && len(records) >= maxRows {
+				return nil, errTooManyRows
+			}
+			records = append(records, record)
+		}
+	}
+	if fields == nil {
+		return nil, errors.New("文件为空")
+	}
+	
+	return records, rows.Error()
+}
+
+func readJSONL(r io.Reader, mapping Mapping, maxRows int) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	
+	var records []Record
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("第 %d 行不是合法的 JSON 对象: %w", line, err)
+		}
+		
+		record := Record{Line: line, Fields: make(map[string]string, len(object))}
+		for key, value := range object {
+			field, ok := mapping(key)
+			if !ok || value == nil {
+				continue
+			}
+			if s, isString := value.(string); isString {
+				record.Fields[field] = s
+			} else {
+				record.Fields[field] = fmt.Sprint(value)
+			}
+		}
+		
+		if maxRows > 0 && len(records) >= maxRows {
+			return nil, errTooManyRows
+		}
+		records = append(records, record)
+	}
+	
+	return records, scanner.Err()
+}
+
+// mapColumns 把表头转换为字段名，未映射的列为空字符串
+func mapColumns(header []string, mapping Mapping) []string {
+	fields := make([]string, len(header))
+	for i, column := range header {
+		if field, ok := mapping(strings.TrimSpace(column)); ok {
+			fields[i] = field
+		}
+	}
+	return fields
+}
+
+// toRecord 按表头组装一行记录，整行为空时返回 false
+func toRecord(line int, fields, row []string) (Record, bool) {
+	record := Record{Line: line, Fields: make(map[string]string, len(fields))}
+	empty := true
+	for i, value := range row {
+		if i >= len(fields) || fields[i] == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value != "" {
+			empty = false
+		}
+		record.Fields[fields[i]] = value
+	}
+	return record, !empty
+}
@@ -0,0 +1,207 @@
+package tabular
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Writer 逐行写出表格数据
+type Writer interface {
+	// WriteRow 写入一行，values 与表头列一一对应
+	WriteRow(values []interface{}) error
+	// Flush 把已缓冲的内容写入底层 io.Writer
+	Flush() error
+	// Close 写出剩余内容并结束文件
+	Close() error
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func ContentType(format Format) string {
+	switch format {
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// NewWriter 创建写出器并写入表头
+func NewWriter(w io.Writer, format Format, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("不支持的文件格式: %s", format)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	// 写入 UTF-8 BOM，Excel 打开时中文不乱码
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// csvFormulaPrefixes 以这些字符开头的单元格会被 Excel 当作公式执行
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// WriteRow 用户输入的文本以公式字符开头时前置单引号，防止 CSV 注入
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatCell(value)
+		if text, ok := value.(string); ok && text != "" && strings.IndexByte(csvFormulaPrefixes, text[0]) >= 0 {
+			record[i] = "'" + text
+		}
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+// WriteRow 按列顺序输出 JSON 对象，保证字段顺序与 fields 参数一致
+func (jw *jsonlWriter) WriteRow(values []interface{}) error {
+	jw.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			jw.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(jw.columns[i])
+		jw.w.Write(key)
+		jw.w.WriteByte(':')
+		
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		jw.w.Write(encoded)
+	}
+	jw.w.WriteByte('}')
+	return jw.w.WriteByte('\n')
+}
+
+func (jw *jsonlWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+func (jw *jsonlWriter) Close() error {
+	return jw.w.Flush()
+}
+
+// xlsxWriter 基于 excelize 的流式写入，行数据超过内存阈值时由 excelize 落盘到临时文件，
+// 文件需在 Close 时整体生成，因此 Flush 不向客户端输出内容
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer, columns []string) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	
+	writer := &xlsxWriter{w: w, file: file, stream: stream}
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	if err := writer.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (xw *xlsxWriter) WriteRow(values []interface{}) error {
+	xw.row++
+	cell, err := excelize.CoordinatesToCellName(1, xw.row)
+	if err != nil {
+		return err
+	}
+	
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = xlsxCell(value)
+	}
+	return xw.stream.SetRow(cell, row)
+}
+
+func (xw *xlsxWriter) Flush() error {
+	return nil
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+	
+	if err := xw.stream.Flush(); err != nil {
+		return err
+	}
+	return xw.file.Write(xw.w)
+}
+
+// formatCell 把值格式化为文本单元格
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// xlsxCell 数字保持数值类型，其余转换为文本
+func xlsxCell(value interface{}) interface{} {
+	switch value.(type) {
+	case int, int64, uint, uint64, float32, float64:
+		return value
+	default:
+		return formatCell(value)
+	}
+}
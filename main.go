@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"topService/internal/cache"
 	"topService/internal/config"
 	"topService/internal/database"
 	"topService/internal/handler"
+	"topService/internal/mailer"
 	"topService/internal/middleware"
+	"topService/internal/model"
 	"topService/internal/router"
 	"topService/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
@@ -36,36 +49,237 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 	
+	// 未配置会话密钥时随机生成，重启后已签发的会话令牌全部失效
+	if cfg.SessionSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("Failed to generate session secret:", err)
+		}
+		cfg.SessionSecret = hex.EncodeToString(secret)
+		log.Println("Warning: SESSION_SECRET not set, using a random secret; sessions will not survive restarts")
+	}
+	
 	// 初始化服务层
-	userService := service.NewUserService(db)
+	jobService := service.NewJobService(db, cfg)
+	userService := service.NewUserService(db, jobService)
 	productService := service.NewProductService(db)
-	movieService := service.NewMovieService(db)
+	appCache := newCache(cfg)
+	movieService := service.NewMovieService(db, appCache, cfg)
+	purgeService := service.NewPurgeService(db, cfg.SoftDeleteRetention, userService, productService, movieService)
+	movieImportService := service.NewMovieImportService(db, movieService, jobService)
+	exportService := service.NewExportService(cfg.ExportDir, jobService, userService, productService, movieService)
+	videoCheckService := service.NewVideoCheckService(db, jobService, cfg)
+	idempotencyService := service.NewIdempotencyService(db, cfg.IdempotencyTTL)
+	apiKeyService := service.NewAPIKeyService(db)
+	sessionService := service.NewSessionService(db, cfg.SessionSecret, cfg.SessionTTL)
+	oidcService := service.NewOIDCService(db, cfg)
+	loginThrottleService := service.NewLoginThrottleService(db, cfg)
+	accountService := service.NewAccountService(db, newMailer(cfg), loginThrottleService, jobService, cfg)
+	twoFactorService := service.NewTwoFactorService(db, loginThrottleService, cfg)
+	auditService := service.NewAuditService(db)
+	webhookService := service.NewWebhookService(db, cfg)
+	eventBroker := service.NewEventBroker(db)
+	scheduler := service.NewScheduler(db, cfg)
 	
 	// 初始化处理器层
-	userHandler := handler.NewUserHandler(userService)
-	productHandler := handler.NewProductHandler(productService)
-	movieHandler := handler.NewMovieHandler(movieService)
+	userHandler := handler.NewUserHandler(userService, exportService)
+	productHandler := handler.NewProductHandler(productService, exportService)
+	movieHandler := handler.NewMovieHandler(movieService, exportService)
+	trashHandler := handler.NewTrashHandler(purgeService)
+	movieImportHandler := handler.NewMovieImportHandler(movieImportService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	authHandler := handler.NewAuthHandler(accountService, oidcService, sessionService, twoFactorService, loginThrottleService, userService, cfg.OIDCPostLoginRedirect)
+	auditHandler := handler.NewAuditHandler(auditService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	eventHandler := handler.NewEventHandler(eventBroker, cfg.EventStreamHeartbeat, cfg.EventStreamMaxDuration)
+	jobHandler := handler.NewJobHandler(jobService, exportService)
+	videoCheckHandler := handler.NewVideoCheckHandler(videoCheckService)
+	taskHandler := handler.NewTaskHandler(scheduler)
+	
+	// 注册后台任务
+	jobService.Register(model.JobMovieImport, movieImportService.RunImportJob)
+	jobService.Register(model.JobExport, exportService.RunExportJob)
+	jobService.Register(model.JobSendEmail, accountService.RunEmailJob)
+	jobService.Register(model.JobCheckVideo, videoCheckService.RunCheckJob)
+	jobService.OnPurge(model.JobExport, exportService.RemoveExport)
+	
+	// 注册定时任务
+	registerTasks(scheduler, cfg, movieService, purgeService, sessionService, videoCheckService)
+	
+	// 首次部署时创建初始 API Key，用于调用管理员接口签发其他密钥
+	if cfg.APIKeyBootstrap != "" {
+		created, err := apiKeyService.EnsureBootstrapKey(context.Background(), cfg.APIKeyBootstrap)
+		if err != nil {
+			log.Fatal("Failed to create bootstrap API key:", err)
+		}
+		if created {
+			log.Println("Bootstrap API key created")
+		}
+	}
+	
+	// 定期清理过期的幂等记录
+	go idempotencyService.Run(context.Background(), cfg.IdempotencyCleanupInterval)
+	
+	// 定期清理过期的登录失败计数
+	go loginThrottleService.Run(context.Background(), cfg.LoginFailureWindow)
+	
+	// 分发发件箱中的领域事件并投递 Webhook
+	go webhookService.Run(context.Background(), cfg.WebhookDispatchInterval)
+	
+	// 向事件流的订阅者推送新事件
+	go eventBroker.Run(context.Background(), cfg.EventStreamPollInterval)
+	
+	// 电影变化后使首页查询的缓存失效
+	go movieService.WatchEvents(context.Background(), eventBroker)
+	
+	// 启动后台任务 worker
+	jobService.Start()
+	
+	// 启动定时任务调度
+	if err := scheduler.Start(context.Background()); err != nil {
+		log.Fatal("Failed to start scheduler:", err)
+	}
 	
 	// 设置运行模式
 	if cfg.AppEnv == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	
+	// 请求 JSON 中出现未知字段时拒绝
+	binding.EnableDecoderDisallowUnknownFields = cfg.DisallowUnknownJSON
+	
 	// 创建路由器
 	r := gin.New()
 	
+	// 只采信可信代理转发的客户端 IP，未配置时直接使用连接地址
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	
 	// 添加中间件
+	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
-	r.Use(middleware.CORS())
+	r.Use(middleware.SecurityHeaders(cfg.HSTSMaxAge))
+	r.Use(middleware.CORS(cfg))
+	if cfg.CompressionEnabled {
+		r.Use(middleware.Compress(cfg.CompressionMinSize))
+	}
+	
+	// 限流，多实例部署时可替换为共享存储
+	rateLimiter := middleware.NewRateLimiter(cfg, middleware.NewMemoryRateLimitStore())
+	
+	// 认证方式，按顺序尝试
+	authenticators := []middleware.Authenticator{
+		middleware.NewSessionAuthenticator(sessionService),
+		middleware.NewAPIKeyAuthenticator(apiKeyService),
+	}
 	
 	// 设置路由
-	router.SetupRoutes(r, userHandler, productHandler, movieHandler)
+	router.SetupRoutes(r, cfg, userHandler, productHandler, movieHandler, movieImportHandler, trashHandler, idempotencyService, rateLimiter, apiKeyHandler, authHandler, auditHandler, webhookHandler, eventHandler, jobHandler, videoCheckHandler, taskHandler, appCache, authenticators)
 	
 	// 启动服务器
-	addr := cfg.ServerHost + ":" + cfg.ServerPort
-	log.Printf("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{
+		Addr:    cfg.ServerHost + ":" + cfg.ServerPort,
+		Handler: r,
+	}
+	// 事件流是长连接，Shutdown 不会等待它们结束，需主动关闭
+	srv.RegisterOnShutdown(eventBroker.Close)
+	
+	go func() {
+		log.Printf("Server starting on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+	
+	// 收到退出信号后停止接收新请求，等待进行中的请求和后台任务结束
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+	
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
+	}
+	if err := scheduler.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Scheduler shutdown: %v, running tasks were interrupted", err)
+	}
+	if err := jobService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Job workers shutdown: %v, unfinished jobs will be resumed", err)
+	}
+	log.Println("Server stopped")
+}
+
+// registerTasks 注册定时任务，cron 表达式无效时退出
+func registerTasks(scheduler *service.Scheduler, cfg *config.Config, movieService *service.MovieService, purgeService *service.PurgeService, sessionService *service.SessionService, videoCheckService *service.VideoCheckService) {
+	tasks := []struct {
+		name string
+		spec string
+		fn   service.ScheduledFunc
+	}{
+		{model.TaskMovieStats, cfg.MovieStatsSchedule, func(ctx context.Context) (interface{}, error) {
+			return movieService.RefreshMovieStats(ctx)
+		}},
+		{model.TaskTrashPurge, cfg.TrashPurgeSchedule, func(ctx context.Context) (interface{}, error) {
+			return purgeService.PurgeExpired(ctx)
+		}},
+		{model.TaskExpireTokens, cfg.ExpireTokensSchedule, func(ctx context.Context) (interface{}, error) {
+			return sessionService.ExpireTokens(ctx)
+		}},
+		// 检查耗时较长，交给后台任务执行，这里只负责创建任务
+		{model.TaskVideoCheck, cfg.VideoCheckSchedule, func(ctx context.Context) (interface{}, error) {
+			job, err := videoCheckService.StartCheck(ctx, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]uint{"job_id": job.ID}, nil
+		}},
+	}
+	for _, task := range tasks {
+		if err := scheduler.Register(task.name, task.spec, task.fn); err != nil {
+			log.Fatal("Failed to register scheduled task:", err)
+		}
+	}
+}
+
+// newCache 按 CACHE_DRIVER 创建查询和响应缓存，none 时返回 nil 表示不缓存
+func newCache(cfg *config.Config) cache.Cache {
+	switch cfg.CacheDriver {
+	case "memory":
+		return cache.NewLRU(cfg.CacheSize)
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		// Redis 暂时不可用时查询直接访问数据库，这里只提示
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			log.Printf("Warning: failed to connect to Redis at %s: %v", cfg.RedisAddr, err)
+		}
+		return cache.NewRedis(client, cfg.RedisKeyPrefix)
+	case "none":
+		return nil
+	default:
+		log.Fatalf("Unknown CACHE_DRIVER: %s", cfg.CacheDriver)
+		return nil
+	}
+}
+
+// newMailer 按 MAIL_DRIVER 创建邮件发送方式
+func newMailer(cfg *config.Config) mailer.Mailer {
+	switch cfg.MailDriver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "file":
+		log.Printf("Mail driver is file, messages are written to %s", cfg.MailOutboxDir)
+		return mailer.NewFileMailer(cfg.MailOutboxDir, cfg.MailFrom)
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER: %s", cfg.MailDriver)
+		return nil
 	}
 }
\ No newline at end of file
@@ -58,8 +58,8 @@ echo ""
 
 # 7. 更新用户
 echo "7. 更新用户..."
-curl -s -X PUT "${BASE_URL}/api/v1/users/${USER_ID}" \
-  -H "Content-Type: application/json" \
+curl -s -X PATCH "${BASE_URL}/api/v1/users/${USER_ID}" \
+  -H "Content-Type: application/merge-patch+json" \
   -d '{
     "username": "updated_user",
     "phone": "13900139000"
@@ -68,8 +68,8 @@ echo ""
 
 # 8. 更新产品
 echo "8. 更新产品..."
-curl -s -X PUT "${BASE_URL}/api/v1/products/${PRODUCT_ID}" \
-  -H "Content-Type: application/json" \
+curl -s -X PATCH "${BASE_URL}/api/v1/products/${PRODUCT_ID}" \
+  -H "Content-Type: application/merge-patch+json" \
   -d '{
     "name": "更新的产品",
     "price": 199.99